@@ -25,27 +25,39 @@ type Options struct {
 	EmdedFS         []embed.FS // Optional embedded filesystem for templates
 	Files           Files
 	TemplateFuncMap template.FuncMap
+	// MarkerPrototypes are the structs the decodeMarkers template func decodes into, by name
+	MarkerPrototypes map[string]any
 }
 
 type OptionsWithCustom[T any] struct {
-	EmdedFS         []embed.FS // Optional embedded filesystem for templates
-	Files           Files
-	TemplateFuncMap template.FuncMap
-	CustomInput     T
+	EmdedFS          []embed.FS // Optional embedded filesystem for templates
+	Files            Files
+	TemplateFuncMap  template.FuncMap
+	MarkerPrototypes map[string]any
+	CustomInput      T
 }
 
 func Execute(parseResults *parse.Results, opts Options) error {
 	return ExecuteWithCustom(parseResults, OptionsWithCustom[struct{}]{
-		EmdedFS:         opts.EmdedFS,
-		Files:           opts.Files,
-		TemplateFuncMap: opts.TemplateFuncMap,
-		CustomInput:     struct{}{},
+		EmdedFS:          opts.EmdedFS,
+		Files:            opts.Files,
+		TemplateFuncMap:  opts.TemplateFuncMap,
+		MarkerPrototypes: opts.MarkerPrototypes,
+		CustomInput:      struct{}{},
 	})
 }
 
 func ExecuteWithCustom[T any](parseResults *parse.Results, opts OptionsWithCustom[T]) error {
 	errs := errorGroup{}
 
+	// Template funcs provided by the parser, overridden by the ones in the options
+	funcMap := parse.ImplementationFuncs(parseResults)
+	funcMap["decodeMarkers"] = parse.DecodeMarkersFunc(opts.MarkerPrototypes)
+	for name, fn := range opts.TemplateFuncMap {
+		funcMap[name] = fn
+	}
+	opts.TemplateFuncMap = funcMap
+
 	input := Input[T]{
 		Results:         parseResults,
 		Package:         &parse.PackageInfo{},
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"github.com/gocloud9/gen-tool/pkg/generate"
 	"github.com/gocloud9/gen-tool/pkg/parse"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"text/template"
@@ -73,3 +75,132 @@ func TestExecute(t *testing.T) {
 
 	fmt.Println(err)
 }
+
+// TestExecute_Snapshot generates from results stored by parse.Marshal, without parsing
+func TestExecute_Snapshot(t *testing.T) {
+	data, err := os.ReadFile("_testdata/snapshot/results.json")
+	if err != nil {
+		t.Fatalf("failed to read snapshot: %v", err)
+	}
+	results, err := parse.Unmarshal(data)
+	if err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	dir := t.TempDir()
+	err = generate.Execute(results, generate.Options{
+		TemplateFuncMap: template.FuncMap{
+			"lower": strings.ToLower,
+		},
+		Files: generate.Files{
+			{
+				DestinationPath: filepath.Join(dir, "fields/{{.Package.Name}}_{{.Struct.Name|lower}}_{{.StructField.Name|lower}}.go"),
+				TemplatePath:    "_testdata/templates/field_template.tmpl",
+				Type:            generate.PerStructField,
+			},
+			{
+				DestinationPath: filepath.Join(dir, "structs/{{.Package.Name}}_{{.Struct.Name|lower}}.go"),
+				TemplatePath:    "_testdata/templates/struct_template.tmpl",
+				Type:            generate.PerStruct,
+			},
+			{
+				DestinationPath: filepath.Join(dir, "packages/{{.Package.Name}}.go"),
+				TemplatePath:    "_testdata/templates/package_template.tmpl",
+				Type:            generate.PerPackage,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	for _, name := range []string{
+		"fields/pkg1_astruct1_afield1.go",
+		"fields/pkg1_astruct1_afield2.go",
+		"structs/pkg1_astruct1.go",
+		"packages/pkg1.go",
+	} {
+		got, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("failed to read generated file: %v", err)
+		}
+		want, err := os.ReadFile(filepath.Join("_testdata/expected", name))
+		if err != nil {
+			t.Fatalf("failed to read expected file: %v", err)
+		}
+		if string(got) != string(want) {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+}
+
+type builderMarkers struct {
+	Builder struct {
+		Name string
+		Max  int
+	} `marker:"gen:builder"`
+}
+
+func TestExecute_DecodeMarkers(t *testing.T) {
+	results := &parse.Results{
+		Packages: map[string]*parse.PackageInfo{
+			"pkg1": {
+				Name: "pkg1",
+				Structs: map[string]*parse.StructInfo{
+					"AStruct1": {
+						Name:    "AStruct1",
+						Markers: map[string]string{"gen:builder:name": "Foo,max=10"},
+					},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		funcMap template.FuncMap
+		want    string
+	}{
+		{
+			name: "registered",
+			want: "AStruct1 Foo 10",
+		},
+		{
+			name: "overridden by the options",
+			funcMap: template.FuncMap{
+				"decodeMarkers": func(name string, markers any) (any, error) {
+					return map[string]map[string]string{"Builder": {"Name": "Bar", "Max": "1"}}, nil
+				},
+			},
+			want: "AStruct1 Bar 1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			err := generate.Execute(results, generate.Options{
+				TemplateFuncMap:  tt.funcMap,
+				MarkerPrototypes: map[string]any{"builder": builderMarkers{}},
+				Files: generate.Files{
+					{
+						DestinationPath: filepath.Join(dir, "{{.Struct.Name}}.txt"),
+						TemplatePath:    "_testdata/templates/markers_template.tmpl",
+						Type:            generate.PerStruct,
+					},
+				},
+			})
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+
+			got, err := os.ReadFile(filepath.Join(dir, "AStruct1.txt"))
+			if err != nil {
+				t.Fatalf("failed to read generated file: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("generated %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
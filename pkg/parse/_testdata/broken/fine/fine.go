@@ -0,0 +1,3 @@
+package fine
+
+type Fine struct{}
@@ -0,0 +1,7 @@
+package importerr
+
+import "github.com/gocloud9/gen-tool/pkg/parse/_testdata/broken/missing"
+
+type Wrapper struct {
+	Value missing.Value
+}
@@ -0,0 +1,4 @@
+package syntaxerr
+
+type Broken struct {
+	Name string
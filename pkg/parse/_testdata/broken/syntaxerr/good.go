@@ -0,0 +1,3 @@
+package syntaxerr
+
+type Good struct{}
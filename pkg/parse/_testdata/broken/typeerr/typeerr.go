@@ -0,0 +1,10 @@
+package typeerr
+
+type Order struct {
+	Customer Missing
+	Name     string
+}
+
+func Total() int {
+	return "total"
+}
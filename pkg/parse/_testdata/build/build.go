@@ -0,0 +1,3 @@
+package build
+
+type Common struct{}
@@ -0,0 +1,3 @@
+package build
+
+type TestHelper struct{}
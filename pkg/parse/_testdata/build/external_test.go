@@ -0,0 +1,3 @@
+package build_test
+
+type External struct{}
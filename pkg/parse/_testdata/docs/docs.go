@@ -0,0 +1,43 @@
+// Package docs has documented declarations.
+package docs
+
+// User is a customer account.
+//
+// +kind=model
+// +table=users
+type User struct {
+	// ID is the primary key.
+	// +readonly
+	ID int
+
+	// Name is shown to other users.
+	Name string
+}
+
+// Greet returns a greeting.
+//
+//go:noinline
+func (u User) Greet() string {
+	return "hello " + u.Name
+}
+
+// Status of an account.
+// +enum
+type Status string
+
+const (
+	// Active accounts can sign in.
+	Active Status = "active"
+	// +deprecated
+	Disabled Status = "disabled"
+)
+
+/*
+Store persists users.
+
++kind=store
+*/
+type Store interface {
+	// Save stores the user.
+	Save(u *User) error
+}
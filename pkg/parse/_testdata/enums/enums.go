@@ -0,0 +1,29 @@
+package enums
+
+type Color int
+
+const (
+	Red Color = iota + 1
+	Green
+	Blue
+)
+
+type Flag uint
+
+const (
+	FlagA Flag = 1 << iota
+	FlagB
+	FlagAB = FlagA | FlagB
+)
+
+const Size = 1 << 3
+
+type Level string
+
+const Debug Level = "debug"
+
+const Info Level = "info"
+
+const Huge = 1 << 100
+
+const MaxUint64 uint64 = 1<<64 - 1
@@ -0,0 +1,48 @@
+package generics
+
+type Number interface {
+	~int | ~int64 | float64
+}
+
+type Integer interface {
+	int
+}
+
+type Bytes interface {
+	[]byte
+}
+
+type Ints interface {
+	List[int]
+}
+
+// +Foo=true
+// +Bar=123
+type Page[T any] struct {
+	Items []T
+	Next  *Page[T]
+}
+
+type Pair[K comparable, V any] struct {
+	Key   K
+	Value V
+}
+
+type Container[T any] interface {
+	Get() T
+}
+
+type List[T any] []T
+
+type Holder struct {
+	Ints  List[int]
+	Pairs Pair[string, int]
+}
+
+func Sum[N Number](in List[N]) N {
+	var total N
+	for _, v := range in {
+		total += v
+	}
+	return total
+}
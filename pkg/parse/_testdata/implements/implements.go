@@ -0,0 +1,56 @@
+package implements
+
+type Greeter interface {
+	Greet() string
+}
+
+type Closer interface {
+	Close() error
+}
+
+type GreetCloser interface {
+	Greeter
+	Closer
+}
+
+type Number interface {
+	~int | ~int64
+}
+
+type Getter[T any] interface {
+	Get() T
+}
+
+type Empty interface{}
+
+type Value struct{}
+
+func (Value) Greet() string {
+	return ""
+}
+
+type Pointer struct{}
+
+func (*Pointer) Greet() string {
+	return ""
+}
+
+func (*Pointer) Close() error {
+	return nil
+}
+
+type Wrapper struct {
+	Value
+}
+
+type Name string
+
+func (n Name) Greet() string {
+	return string(n)
+}
+
+type Box[T any] struct{}
+
+func (Box[T]) Greet() string {
+	return ""
+}
@@ -0,0 +1,11 @@
+package remote
+
+type Namer interface {
+	Greet() string
+}
+
+type Remote struct{}
+
+func (*Remote) Greet() string {
+	return ""
+}
@@ -0,0 +1,24 @@
+package literals
+
+type Reader interface {
+	Read(p []byte) (n int, err error)
+}
+
+type Literals struct {
+	Hash     [4]byte
+	Matrix   [2][3]int
+	Receive  <-chan string
+	Send     chan<- string
+	Channels chan (<-chan int)
+	Inline   struct {
+		// +Foo=true
+		Name string `json:"name"`
+		Reader
+	}
+	Handler interface {
+		Reader
+		// +Foo=true
+		Close() error
+	}
+	Empty struct{}
+}
@@ -0,0 +1,13 @@
+package markers
+
+// Config is generated.
+// +gen:builder:name=Foo,skip={A,B},max=10,strict=true
+// +validate:enum=a
+// +validate:enum=b
+// +kubebuilder:printcolumn:name="Age",type=date,labels={app:"web",tier:2}
+// +sizes=1;2;3
+// +deprecated
+type Config struct {
+	// +default="x, y"
+	Name string
+}
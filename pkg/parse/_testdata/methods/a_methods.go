@@ -0,0 +1,42 @@
+package methods
+
+func (c Color) String() string {
+	return ""
+}
+
+func (c *Color) Set(s string) {
+}
+
+func (l *List[T]) Push(v T) {
+	l.items = append(l.items, v)
+}
+
+func (l List[T]) Len() int {
+	return len(l.items)
+}
+
+func (r *Reference) Update() {
+}
+
+func (r Reference) Get() string {
+	return ""
+}
+
+func (Reference) Static() {
+}
+
+func (c Colour) Name() string {
+	return ""
+}
+
+func String() string {
+	return ""
+}
+
+func Len() int {
+	return 0
+}
+
+func (h *Hidden) Close() error {
+	return nil
+}
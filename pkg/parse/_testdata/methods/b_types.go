@@ -0,0 +1,19 @@
+package methods
+
+type Color int
+
+type List[T any] struct {
+	items []T
+}
+
+type Base struct{}
+
+func (Base) Hello() {}
+
+func (*Base) Bye() {}
+
+type Reference struct {
+	Base
+}
+
+type Colour = Color
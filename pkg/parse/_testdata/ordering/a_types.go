@@ -0,0 +1,27 @@
+package ordering
+
+const (
+	Zeta  = "z"
+	Alpha = "a"
+)
+
+var (
+	Second = "2"
+	First  = "1"
+)
+
+type Record struct {
+	ZField string
+	AField int
+	X, B   bool
+	Mid    string
+}
+
+type Service interface {
+	Stop() error
+	Start() error
+}
+
+type Beta string
+
+type Able string
@@ -0,0 +1,7 @@
+package ordering
+
+func (Record) Zed() {}
+
+func (Record) Abc() {}
+
+func Run() {}
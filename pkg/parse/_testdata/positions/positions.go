@@ -0,0 +1,22 @@
+package positions
+
+const Answer = 42
+
+var Greeting = "hello"
+
+type Kind string
+
+type Alias = Kind
+
+type Shape interface {
+	Area() float64
+}
+
+type Square struct {
+	Side float64
+	Kind
+}
+
+func NewSquare(side float64) *Square {
+	return &Square{Side: side}
+}
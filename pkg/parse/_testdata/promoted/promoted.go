@@ -0,0 +1,73 @@
+package promoted
+
+import (
+	"io"
+	"sync"
+)
+
+type Base[T any] struct {
+	Value T
+	ID    int
+}
+
+func (b Base[T]) Get() T {
+	return b.Value
+}
+
+type Parent struct {
+	// +Foo=true
+	Name string `json:"name"`
+	ID   string
+	Age  int
+}
+
+func (p *Parent) Rename(name string) {
+	p.Name = name
+}
+
+// +Bar=123
+func (p Parent) Describe() string {
+	return p.Name
+}
+
+type Inner struct {
+	Depth int
+}
+
+type Middle struct {
+	Inner
+	Name string
+}
+
+type Child struct {
+	*Parent
+	io.Reader
+	sync.Mutex
+	Base[string]
+	Middle
+	Age int
+}
+
+type Stringer interface {
+	String() string
+}
+
+type Getter[T any] interface {
+	Get() T
+}
+
+type ReadLocker interface {
+	io.Reader
+	sync.Locker
+	Stringer
+	Getter[string]
+}
+
+type Holder struct {
+	Locker interface {
+		io.Reader
+		sync.Locker
+		Stringer
+		Getter[string]
+	}
+}
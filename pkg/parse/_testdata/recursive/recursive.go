@@ -0,0 +1,41 @@
+package recursive
+
+import "time"
+
+type List []List
+
+type Node struct {
+	Value    int
+	Next     *Node
+	Children map[string]*Node
+}
+
+type A struct {
+	B *B
+}
+
+type B struct {
+	As []A
+}
+
+type Tree[T any] struct {
+	Value    T
+	Children []*Tree[T]
+}
+
+type Visitor interface {
+	Visit(n *Node) Visitor
+}
+
+type Leaf struct {
+	Name    string
+	Created time.Time
+}
+
+type Holder struct {
+	Node Node
+	Leaf Leaf
+	Tree Tree[int]
+}
+
+type Alias = Node
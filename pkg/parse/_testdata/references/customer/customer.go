@@ -0,0 +1,18 @@
+package customer
+
+type Customer struct {
+	Name    string
+	Address Address
+}
+
+type Address struct {
+	Street string
+}
+
+type Status int
+
+type Handler interface {
+	Handle(c *Customer) error
+}
+
+type Account = Customer
@@ -0,0 +1,24 @@
+package references
+
+import (
+	"time"
+
+	"github.com/gocloud9/gen-tool/pkg/parse/_testdata/references/customer"
+)
+
+type Order struct {
+	Customer customer.Customer
+	Account  customer.Account
+	Items    []*Item
+	Status   customer.Status
+	Handler  customer.Handler
+	Created  time.Time
+}
+
+type Item struct {
+	Order *Order
+}
+
+func Process(o *Order) map[string]customer.Customer {
+	return nil
+}
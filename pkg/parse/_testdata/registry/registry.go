@@ -0,0 +1,24 @@
+package registry
+
+// +gen:builder:name=Foo,max=10
+// +gen:buidler
+type Config struct {
+	// +gen:builder:name=Bar
+	Name string
+
+	// +validate:enum=a;b
+	// +validate:max=ten
+	Level string
+
+	// +validate:enum={a:b}
+	Mode string
+}
+
+// +validate:enum=x
+func Run() {}
+
+// +gen:builder:max=3
+type Options struct {
+	// +validate:max={1
+	Size int
+}
@@ -0,0 +1,3 @@
+package resolve
+
+type Kind string
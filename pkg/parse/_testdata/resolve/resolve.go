@@ -0,0 +1,10 @@
+package resolve
+
+import (
+	. "time"
+)
+
+type Event struct {
+	Kind    Kind
+	Timeout Duration
+}
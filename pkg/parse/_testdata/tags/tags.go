@@ -0,0 +1,13 @@
+package tags
+
+type Base struct{}
+
+type User struct {
+	Base `yaml:",inline"`
+
+	ID    int    `json:"id,omitempty,string" db:"user_id"`
+	Role  string `validate:"oneof=admin member" description:"The user role"`
+	Email string "json:\"email\""
+	Note  string `json:"-" json:"note"`
+	Plain string
+}
@@ -0,0 +1,30 @@
+package values
+
+import "strings"
+
+type Kind int
+
+// +Foo=true
+const (
+	KindA Kind = iota
+	KindB
+	// +Bar=123
+	KindC
+)
+
+const Ratio, Scale = 1.5, 2
+
+var builder *strings.Builder
+
+var a, b int = 1, 2
+
+var _ = a
+
+func local() {
+	var hidden = 1
+	_ = hidden
+
+	type Kind struct{ Hidden int }
+	type Local int
+	_, _ = Kind{}, Local(0)
+}
@@ -0,0 +1,79 @@
+package parse
+
+import (
+	"golang.org/x/tools/go/packages"
+	"os"
+	"runtime"
+	"strings"
+)
+
+// BuildInfo is the build configuration a package was loaded with
+type BuildInfo struct {
+	GOOS   string
+	GOARCH string
+	Tags   []string
+	// Tests is set when the packages were loaded with Options.Tests
+	Tests bool
+	// IsTest is set for a package compiled with its _test.go files and for its p_test package
+	IsTest bool
+}
+
+// buildConfig returns the environment and build flags go list runs with
+func buildConfig(opts Options) ([]string, []string) {
+	env := append(os.Environ(), opts.Env...)
+	if opts.GOOS != "" {
+		env = append(env, "GOOS="+opts.GOOS)
+	}
+	if opts.GOARCH != "" {
+		env = append(env, "GOARCH="+opts.GOARCH)
+	}
+
+	flags := []string{}
+	if len(opts.BuildTags) > 0 {
+		flags = append(flags, "-tags="+strings.Join(opts.BuildTags, ","))
+	}
+
+	return env, flags
+}
+
+func buildInfo(opts Options, env []string, pkg *packages.Package) *BuildInfo {
+	return &BuildInfo{
+		GOOS:   envValue(env, "GOOS", runtime.GOOS),
+		GOARCH: envValue(env, "GOARCH", runtime.GOARCH),
+		Tags:   append([]string{}, opts.BuildTags...),
+		Tests:  opts.Tests,
+		IsTest: pkg.ForTest != "",
+	}
+}
+
+// envValue returns the last value of the variable, like os/exec does with duplicates
+func envValue(env []string, name, fallback string) string {
+	for i := len(env) - 1; i >= 0; i-- {
+		if value, ok := strings.CutPrefix(env[i], name+"="); ok && value != "" {
+			return value
+		}
+	}
+
+	return fallback
+}
+
+// testVariants replaces the packages by their variant compiled with the _test.go
+// files and drops the generated test main packages
+func testVariants(pkgs []*packages.Package) []*packages.Package {
+	tested := map[string]bool{}
+	for _, pkg := range pkgs {
+		if pkg.ForTest != "" && pkg.PkgPath == pkg.ForTest {
+			tested[pkg.PkgPath] = true
+		}
+	}
+
+	variants := []*packages.Package{}
+	for _, pkg := range pkgs {
+		if pkg.ForTest == "" && (tested[pkg.PkgPath] || strings.HasSuffix(pkg.ID, ".test")) {
+			continue
+		}
+		variants = append(variants, pkg)
+	}
+
+	return variants
+}
@@ -0,0 +1,305 @@
+package parse
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"go/types"
+	"golang.org/x/tools/go/packages"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// cacheVersion changes with the layout of the cached packages, entries of other versions are never read
+const cacheVersion = "1"
+
+// modulePath is the module of the tool, its version is part of the cache keys
+const modulePath = "github.com/gocloud9/gen-tool"
+
+// cachedPackage is what the cache keeps of a parsed package, before it is linked to the other packages
+type cachedPackage struct {
+	Info        *PackageInfo
+	Diagnostics []Diagnostic
+	// References are the TypeInfos of Info naming a type, Types every type met or declared
+	References map[string][]*TypeInfo
+	Types      []string
+}
+
+// packageCache reads and writes the parsed packages in Options.CacheDir. An entry
+// is keyed by the hash of the package files, of its dependencies, of the build and
+// parse options and of the tool version, so a changed file only misses the entries
+// of its package and of the packages importing it.
+type packageCache struct {
+	dir     string
+	options string
+	overlay map[string][]byte
+	hashes  map[*packages.Package]string
+}
+
+func newPackageCache(opts Options, env []string, overlay map[string][]byte) *packageCache {
+	if opts.CacheDir == "" {
+		return nil
+	}
+
+	return &packageCache{
+		dir:     opts.CacheDir,
+		options: cacheOptions(opts, env),
+		overlay: overlay,
+		hashes:  map[*packages.Package]string{},
+	}
+}
+
+// hashPackages computes the keys of the packages and their dependencies, it is
+// called before the packages are parsed so the workers only read the hashes
+func (c *packageCache) hashPackages(pkgs []*packages.Package) {
+	if c == nil {
+		return
+	}
+
+	for _, pkg := range pkgs {
+		c.hash(pkg)
+	}
+}
+
+// parse returns the cached package, or parses it and caches it. Entries that
+// cannot be read or written are ignored and the package is parsed.
+func (c *packageCache) parse(pkg *packages.Package, opts Options, env []string) parsedPackage {
+	if c == nil || c.hashes[pkg] == "" {
+		return parsePackage(pkg, opts, env)
+	}
+
+	path := filepath.Join(c.dir, c.hashes[pkg]+".json")
+	if pp, ok := c.read(path, pkg); ok {
+		return pp
+	}
+
+	pp := parsePackage(pkg, opts, env)
+	_ = c.write(path, pp)
+
+	return pp
+}
+
+func (c *packageCache) read(path string, pkg *packages.Package) (parsedPackage, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return parsedPackage{}, false
+	}
+
+	cp := &cachedPackage{}
+	if err := unmarshalGraph(data, cp); err != nil || cp.Info == nil {
+		return parsedPackage{}, false
+	}
+
+	references := newTypeReferences()
+	for key, infos := range cp.References {
+		references.infos[key] = infos
+	}
+	for _, key := range cp.Types {
+		obj := lookupTypeName(pkg, key)
+		if obj == nil {
+			return parsedPackage{}, false
+		}
+		references.objects[key] = obj
+	}
+
+	return parsedPackage{info: cp.Info, diagnostics: cp.Diagnostics, references: references}, true
+}
+
+func (c *packageCache) write(path string, pp parsedPackage) error {
+	cp := &cachedPackage{
+		Info:        pp.info,
+		Diagnostics: pp.diagnostics,
+		References:  pp.references.infos,
+		Types:       make([]string, 0, len(pp.references.objects)),
+	}
+	for key := range pp.references.objects {
+		cp.Types = append(cp.Types, key)
+	}
+	sort.Strings(cp.Types)
+
+	data, err := marshalGraph(cp)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		return err
+	}
+	// Written aside and renamed so concurrent runs never read half an entry
+	tmp, err := os.CreateTemp(c.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// hash returns the key of the package, empty when a file cannot be read and the package is not cached
+func (c *packageCache) hash(pkg *packages.Package) string {
+	if hash, ok := c.hashes[pkg]; ok {
+		return hash
+	}
+	// Import cycles are type errors, the package is not cached while its hash is computed
+	c.hashes[pkg] = ""
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n", cacheVersion, c.options)
+	fmt.Fprintf(h, "package %q %q %q %q %q\n", pkg.ID, pkg.PkgPath, pkg.Name, pkg.Dir, pkg.ForTest)
+
+	if pkg.Module != nil && !pkg.Module.Main && pkg.Module.Replace == nil && pkg.Module.Version != "" {
+		// Module versions are immutable, the version stands for the files
+		fmt.Fprintf(h, "module %s@%s\n", pkg.Module.Path, pkg.Module.Version)
+	} else {
+		files := append(append([]string{}, pkg.GoFiles...), pkg.CompiledGoFiles...)
+		for _, file := range files {
+			if !c.hashFile(h, file) {
+				return ""
+			}
+		}
+	}
+
+	imports := make([]string, 0, len(pkg.Imports))
+	for path := range pkg.Imports {
+		imports = append(imports, path)
+	}
+	sort.Strings(imports)
+	for _, path := range imports {
+		dep := c.hash(pkg.Imports[path])
+		if dep == "" {
+			return ""
+		}
+		fmt.Fprintf(h, "import %q %s\n", path, dep)
+	}
+
+	hash := hex.EncodeToString(h.Sum(nil))
+	c.hashes[pkg] = hash
+
+	return hash
+}
+
+func (c *packageCache) hashFile(h io.Writer, file string) bool {
+	content, ok := c.overlay[file]
+	if !ok {
+		var err error
+		if content, err = os.ReadFile(file); err != nil {
+			return false
+		}
+	}
+
+	sum := sha256.Sum256(content)
+	fmt.Fprintf(h, "file %q %x\n", file, sum)
+
+	return true
+}
+
+// cacheOptions describes the options and the build changing what is parsed
+func cacheOptions(opts Options, env []string) string {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "tool %s\n", toolVersion())
+	fmt.Fprintf(b, "build %s %s %q %t %q\n", envValue(env, "GOOS", ""), envValue(env, "GOARCH", ""), opts.BuildTags, opts.Tests, opts.Env)
+	fmt.Fprintf(b, "parse %q %t\n", opts.MarkerPrefix, opts.IncludePromoted)
+	for _, re := range opts.SkipFilesWithContentsRegex {
+		fmt.Fprintf(b, "skip %q\n", re.String())
+	}
+
+	if opts.Markers != nil {
+		names := make([]string, 0, len(opts.Markers.definitions))
+		for name := range opts.Markers.definitions {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(b, "marker %+v\n", *opts.Markers.definitions[name])
+		}
+	}
+
+	return b.String()
+}
+
+// toolVersion is the version of the tool module in the running binary, entries
+// written by a development build are keyed by the binary itself
+var toolVersion = sync.OnceValue(func() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+
+	modules := append([]*debug.Module{&info.Main}, info.Deps...)
+	for _, module := range modules {
+		if module.Path != modulePath {
+			continue
+		}
+		if module.Replace != nil {
+			module = module.Replace
+		}
+		if module.Version != "" && module.Version != "(devel)" {
+			return module.Version
+		}
+	}
+
+	return executableHash()
+})
+
+func executableHash() string {
+	path, err := os.Executable()
+	if err != nil {
+		return "unknown"
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return "unknown"
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "unknown"
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// lookupTypeName finds the type "pkgpath.Name" in the package or in its dependencies
+func lookupTypeName(pkg *packages.Package, key string) *types.TypeName {
+	dot := strings.LastIndex(key, ".")
+	if dot < 0 {
+		return nil
+	}
+	path, name := key[:dot], key[dot+1:]
+
+	var found *types.TypeName
+	seen := map[*packages.Package]bool{}
+	queue := []*packages.Package{pkg}
+	for len(queue) > 0 && found == nil {
+		next := queue[0]
+		queue = queue[1:]
+		if seen[next] {
+			continue
+		}
+		seen[next] = true
+
+		if next.PkgPath == path && next.Types != nil {
+			found, _ = next.Types.Scope().Lookup(name).(*types.TypeName)
+			continue
+		}
+		for _, dep := range next.Imports {
+			queue = append(queue, dep)
+		}
+	}
+
+	return found
+}
@@ -0,0 +1,74 @@
+package parse
+
+import (
+	"go/constant"
+	"strconv"
+)
+
+type ConstantKind string
+
+const (
+	ConstantBool    ConstantKind = "bool"
+	ConstantString  ConstantKind = "string"
+	ConstantInt     ConstantKind = "int"
+	ConstantFloat   ConstantKind = "float"
+	ConstantComplex ConstantKind = "complex"
+)
+
+func setConstantValue(ci *ConstantInfo, val constant.Value) {
+	if val == nil || val.Kind() == constant.Unknown {
+		return
+	}
+
+	ci.ExactValue = val.ExactString()
+
+	switch val.Kind() {
+	case constant.Bool:
+		ci.Kind = ConstantBool
+		ci.BoolValue = constant.BoolVal(val)
+	case constant.String:
+		ci.Kind = ConstantString
+		ci.StringValue = constant.StringVal(val)
+	case constant.Int:
+		ci.Kind = ConstantInt
+		ci.IntValue, ci.IntExact = constant.Int64Val(val)
+		ci.FloatValue, _ = constant.Float64Val(val)
+	case constant.Float:
+		var exact bool
+		ci.Kind = ConstantFloat
+		ci.FloatValue, exact = constant.Float64Val(val)
+		// Floats are exact fractions, keep a Go literal when the value has one
+		if exact {
+			ci.ExactValue = strconv.FormatFloat(ci.FloatValue, 'g', -1, 64)
+		}
+	case constant.Complex:
+		ci.Kind = ConstantComplex
+	}
+}
+
+// collectEnums groups the constants of each defined type declared in the package
+func collectEnums(pi *PackageInfo) {
+	for _, ci := range pi.ConstantList {
+		dti, ok := pi.DefinedTypes[ci.TypeName]
+		if !ok {
+			continue
+		}
+
+		ei, ok := pi.Enums[dti.Name]
+		if !ok {
+			ei = &EnumInfo{
+				Name:       dti.Name,
+				Position:   dti.Position,
+				Markers:    dti.Markers,
+				MarkerList: dti.MarkerList,
+				Doc:        dti.Doc,
+				Type:       dti,
+			}
+			pi.Enums[ei.Name] = ei
+		}
+
+		ei.Values = append(ei.Values, ci)
+	}
+
+	pi.EnumList = sortedByPosition(pi.Enums, func(ei *EnumInfo) Position { return ei.Position })
+}
@@ -0,0 +1,325 @@
+package parse
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// DecodeMarkers sets the fields of the struct v points to from the markers.
+// A field takes the marker named by its `marker:"name"` tag, or by the field
+// name, compared case-insensitively; "-" skips the field. Flag markers such as
+// "+deprecated" decode as true, the value of "+max=10" as the marker value and
+// markers with named arguments, "+gen:builder:name=Foo,max=10", into a struct
+// or map whose fields are matched with the argument names the same way.
+// Values are converted to strings, ints, uints, floats, bools, time.Duration,
+// slices, maps, pointers and nested structs. A leading "+", kept in the names
+// when the markers are parsed without a MarkerPrefix, is ignored.
+func DecodeMarkers(markers map[string]string, v any) error {
+	keys := make([]string, 0, len(markers))
+	for key := range markers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	list := make([]*Marker, 0, len(keys))
+	for _, key := range keys {
+		if markers[key] == "" {
+			list = append(list, parseMarker(key))
+		} else {
+			list = append(list, parseMarker(key+"="+markers[key]))
+		}
+	}
+
+	return DecodeMarkerList(list, v)
+}
+
+// DecodeMarkerList is DecodeMarkers for a MarkerList, repeated markers append to slice fields
+func DecodeMarkerList(markers []*Marker, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("decode markers: want a pointer to a struct, got %T", v)
+	}
+
+	st := rv.Elem()
+	for i := 0; i < st.NumField(); i++ {
+		sf := st.Type().Field(i)
+		name, ok := decodeFieldName(sf)
+		if !ok {
+			continue
+		}
+
+		matched := []*Marker{}
+		for _, m := range markers {
+			if mm, ok := markerNamed(m, name); ok {
+				matched = append(matched, mm)
+			}
+		}
+
+		for j, m := range matched {
+			if m.err != nil {
+				return fmt.Errorf("decode marker %q: %w", m.Name, m.err)
+			}
+
+			var err error
+			if st.Field(i).Kind() == reflect.Slice && j > 0 {
+				next := reflect.New(sf.Type).Elem()
+				if err = decodeMarkerValue(next, markerArgValue(m)); err == nil {
+					st.Field(i).Set(reflect.AppendSlice(st.Field(i), next))
+				}
+			} else {
+				err = decodeMarkerValue(st.Field(i), markerArgValue(m))
+			}
+			if err != nil {
+				return fmt.Errorf("decode marker %q into %s: %w", m.Name, sf.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// DecodeMarkersFunc returns a template func decoding a Markers map or a MarkerList
+// into a copy of the prototype registered under the name, e.g.
+// {{ $cfg := decodeMarkers "builder" .Struct.Markers }}
+func DecodeMarkersFunc(prototypes map[string]any) func(name string, markers any) (any, error) {
+	return func(name string, markers any) (any, error) {
+		prototype, ok := prototypes[name]
+		if !ok {
+			return nil, fmt.Errorf("decode markers: no prototype named %q", name)
+		}
+
+		rv := reflect.New(reflect.TypeOf(prototype))
+		rv.Elem().Set(reflect.ValueOf(prototype))
+
+		var err error
+		switch m := markers.(type) {
+		case map[string]string:
+			err = DecodeMarkers(m, rv.Interface())
+		case []*Marker:
+			err = DecodeMarkerList(m, rv.Interface())
+		default:
+			err = fmt.Errorf("decode markers: want a Markers map or a MarkerList, got %T", markers)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		return rv.Elem().Interface(), nil
+	}
+}
+
+func decodeFieldName(sf reflect.StructField) (string, bool) {
+	if !sf.IsExported() {
+		return "", false
+	}
+
+	name := sf.Tag.Get("marker")
+	if name == "-" {
+		return "", false
+	}
+	if name == "" {
+		name = sf.Name
+	}
+
+	return name, true
+}
+
+// markerNamed matches the marker with the name, a marker with a single named
+// argument such as "+gen:builder:name=Foo" also matches "gen:builder"
+func markerNamed(m *Marker, name string) (*Marker, bool) {
+	m = withoutMarkerSign(m)
+	if strings.EqualFold(m.Name, name) {
+		return m, true
+	}
+
+	value, single := m.Args[""]
+	if !single || len(m.Path) < 2 || !strings.EqualFold(strings.Join(m.Path[:len(m.Path)-1], ":"), name) {
+		return nil, false
+	}
+
+	argName := m.Path[len(m.Path)-1]
+
+	return &Marker{
+		Name:     name,
+		Path:     m.Path[:len(m.Path)-1],
+		Raw:      m.Raw,
+		Position: m.Position,
+		Args:     map[string]*MarkerValue{argName: value},
+		ArgNames: []string{argName},
+	}, true
+}
+
+// withoutMarkerSign returns the marker without the "+" starting its name, which
+// stays when the comments are parsed with the default empty MarkerPrefix
+func withoutMarkerSign(m *Marker) *Marker {
+	if !strings.HasPrefix(m.Name, "+") {
+		return m
+	}
+
+	trimmed := *m
+	trimmed.Name = strings.TrimPrefix(m.Name, "+")
+	trimmed.Path = append([]string{strings.TrimPrefix(m.Path[0], "+")}, m.Path[1:]...)
+
+	return &trimmed
+}
+
+// markerArgValue returns true for flag markers, the value of "+name=value" and a map of the named arguments otherwise
+func markerArgValue(m *Marker) *MarkerValue {
+	if len(m.Args) == 0 {
+		return &MarkerValue{Kind: MarkerValueBool, Raw: "true", BoolValue: true}
+	}
+	if value, ok := m.Args[""]; ok && len(m.Args) == 1 {
+		return value
+	}
+
+	return &MarkerValue{Kind: MarkerValueMap, Raw: m.Raw, Map: m.Args}
+}
+
+func decodeMarkerValue(fv reflect.Value, v *MarkerValue) error {
+	mismatch := func() error {
+		return fmt.Errorf("cannot decode %s %q into %s", v.Kind, v.Raw, fv.Type())
+	}
+
+	if fv.Type() == durationType {
+		switch v.Kind {
+		case MarkerValueString:
+			d, err := time.ParseDuration(v.StringValue)
+			if err != nil {
+				return fmt.Errorf("cannot decode %q into %s: %w", v.Raw, fv.Type(), err)
+			}
+			fv.SetInt(int64(d))
+		case MarkerValueInt:
+			fv.SetInt(v.IntValue)
+		default:
+			return mismatch()
+		}
+
+		return nil
+	}
+
+	switch fv.Kind() {
+	case reflect.Pointer:
+		elem := reflect.New(fv.Type().Elem())
+		if err := decodeMarkerValue(elem.Elem(), v); err != nil {
+			return err
+		}
+		fv.Set(elem)
+	case reflect.Interface:
+		if fv.NumMethod() != 0 {
+			return mismatch()
+		}
+		fv.Set(reflect.ValueOf(v.Value()))
+	case reflect.String:
+		switch v.Kind {
+		case MarkerValueString:
+			fv.SetString(v.StringValue)
+		case MarkerValueInt, MarkerValueBool:
+			fv.SetString(v.Raw)
+		default:
+			return mismatch()
+		}
+	case reflect.Bool:
+		if v.Kind != MarkerValueBool {
+			return mismatch()
+		}
+		fv.SetBool(v.BoolValue)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Kind != MarkerValueInt {
+			return mismatch()
+		}
+		if fv.OverflowInt(v.IntValue) {
+			return fmt.Errorf("%d overflows %s", v.IntValue, fv.Type())
+		}
+		fv.SetInt(v.IntValue)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if v.Kind != MarkerValueInt || v.IntValue < 0 {
+			return mismatch()
+		}
+		if fv.OverflowUint(uint64(v.IntValue)) {
+			return fmt.Errorf("%d overflows %s", v.IntValue, fv.Type())
+		}
+		fv.SetUint(uint64(v.IntValue))
+	case reflect.Float32, reflect.Float64:
+		switch v.Kind {
+		case MarkerValueInt:
+			fv.SetFloat(float64(v.IntValue))
+		case MarkerValueString:
+			f, err := strconv.ParseFloat(v.StringValue, fv.Type().Bits())
+			if err != nil {
+				return mismatch()
+			}
+			fv.SetFloat(f)
+		default:
+			return mismatch()
+		}
+	case reflect.Slice:
+		items := v.List
+		if v.Kind != MarkerValueList {
+			items = []*MarkerValue{v}
+		}
+		slice := reflect.MakeSlice(fv.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := decodeMarkerValue(slice.Index(i), item); err != nil {
+				return fmt.Errorf("item %d: %w", i, err)
+			}
+		}
+		fv.Set(slice)
+	case reflect.Map:
+		if v.Kind != MarkerValueMap || fv.Type().Key().Kind() != reflect.String {
+			return mismatch()
+		}
+		m := reflect.MakeMapWithSize(fv.Type(), len(v.Map))
+		for key, item := range v.Map {
+			elem := reflect.New(fv.Type().Elem()).Elem()
+			if err := decodeMarkerValue(elem, item); err != nil {
+				return fmt.Errorf("key %q: %w", key, err)
+			}
+			m.SetMapIndex(reflect.ValueOf(key).Convert(fv.Type().Key()), elem)
+		}
+		fv.Set(m)
+	case reflect.Struct:
+		if v.Kind != MarkerValueMap {
+			return mismatch()
+		}
+		return decodeMarkerStruct(fv, v.Map)
+	default:
+		return fmt.Errorf("unsupported field type %s", fv.Type())
+	}
+
+	return nil
+}
+
+func decodeMarkerStruct(fv reflect.Value, args map[string]*MarkerValue) error {
+	keys := make([]string, 0, len(args))
+	for key := range args {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		found := false
+		for i := 0; i < fv.NumField(); i++ {
+			name, ok := decodeFieldName(fv.Type().Field(i))
+			if !ok || !strings.EqualFold(name, key) {
+				continue
+			}
+
+			found = true
+			if err := decodeMarkerValue(fv.Field(i), args[key]); err != nil {
+				return fmt.Errorf("argument %q: %w", key, err)
+			}
+			break
+		}
+		if !found {
+			return fmt.Errorf("unknown argument %q for %s", key, fv.Type())
+		}
+	}
+
+	return nil
+}
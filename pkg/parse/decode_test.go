@@ -0,0 +1,179 @@
+package parse_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/gocloud9/gen-tool/pkg/parse"
+	"github.com/google/go-cmp/cmp"
+)
+
+type builderConfig struct {
+	Name   string
+	Skip   []string
+	Max    int
+	Strict bool
+}
+
+type markerConfig struct {
+	Builder    builderConfig     `marker:"gen:builder"`
+	Enum       []string          `marker:"validate:enum"`
+	Timeout    time.Duration     `marker:"timeout"`
+	Deprecated bool              `marker:"deprecated"`
+	Ratio      *float64          `marker:"ratio"`
+	Labels     map[string]string `marker:"labels"`
+	Ignored    string            `marker:"-"`
+}
+
+func TestDecodeMarkers(t *testing.T) {
+	ratio := 0.5
+
+	tests := []struct {
+		name    string
+		markers map[string]string
+		want    markerConfig
+		wantErr string
+	}{
+		{
+			name: "all kinds",
+			markers: map[string]string{
+				"gen:builder:name": "Foo,skip={A,B},max=10,strict=true",
+				"validate:enum":    "a;b",
+				"timeout":          "1m30s",
+				"deprecated":       "",
+				"ratio":            "0.5",
+				"labels":           `{app:"web",tier:2}`,
+				"-":                "x",
+				"unrelated":        "y",
+			},
+			want: markerConfig{
+				Builder:    builderConfig{Name: "Foo", Skip: []string{"A", "B"}, Max: 10, Strict: true},
+				Enum:       []string{"a", "b"},
+				Timeout:    90 * time.Second,
+				Deprecated: true,
+				Ratio:      &ratio,
+				Labels:     map[string]string{"app": "web", "tier": "2"},
+			},
+		},
+		{
+			name:    "single named argument",
+			markers: map[string]string{"gen:builder:name": "Foo", "validate:enum": "a"},
+			want:    markerConfig{Builder: builderConfig{Name: "Foo"}, Enum: []string{"a"}},
+		},
+		{
+			name:    "int mismatch",
+			markers: map[string]string{"gen:builder:name": "Foo,max=ten"},
+			wantErr: `decode marker "gen:builder" into Builder: argument "max": cannot decode string "ten" into int`,
+		},
+		{
+			name:    "unknown argument",
+			markers: map[string]string{"gen:builder:name": "Foo,min=1"},
+			wantErr: `decode marker "gen:builder" into Builder: unknown argument "min" for parse_test.builderConfig`,
+		},
+		{
+			name:    "bad duration",
+			markers: map[string]string{"timeout": "soon"},
+			wantErr: `decode marker "timeout" into Timeout: cannot decode "soon" into time.Duration: time: invalid duration "soon"`,
+		},
+		{
+			name:    "malformed marker",
+			markers: map[string]string{"labels": "{app"},
+			wantErr: `decode marker "labels": unterminated { in {app`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := markerConfig{}
+			err := parse.DecodeMarkers(tt.markers, &got)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("DecodeMarkers() error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DecodeMarkers() error = %v", err)
+			}
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	if err := parse.DecodeMarkers(map[string]string{}, markerConfig{}); err == nil {
+		t.Errorf("DecodeMarkers() into a non pointer should fail")
+	}
+}
+
+func TestDecodeMarkerList(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get working directory: %v", err)
+	}
+
+	p := &parse.Parser{}
+	got, err := p.ParseDirectory(parse.Options{Path: filepath.Join(wd, "./_testdata/markers"), MarkerPrefix: "+"})
+	if err != nil {
+		t.Fatalf("ParseDirectory() error = %v", err)
+	}
+
+	si := got.Packages["github.com/gocloud9/gen-tool/pkg/parse/_testdata/markers"].Structs["Config"]
+
+	cfg := markerConfig{}
+	if err := parse.DecodeMarkerList(si.MarkerList, &cfg); err != nil {
+		t.Fatalf("DecodeMarkerList() error = %v", err)
+	}
+
+	want := markerConfig{
+		Builder:    builderConfig{Name: "Foo", Skip: []string{"A", "B"}, Max: 10, Strict: true},
+		Enum:       []string{"a", "b"},
+		Deprecated: true,
+	}
+	if diff := cmp.Diff(want, cfg); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	tmpl := template.Must(template.New("t").Funcs(template.FuncMap{
+		"decodeMarkers": parse.DecodeMarkersFunc(map[string]any{"config": markerConfig{Ignored: "default"}}),
+	}).Parse(`{{ $cfg := decodeMarkers "config" .MarkerList }}{{ $cfg.Builder.Name }} {{ $cfg.Enum }} {{ $cfg.Ignored }}`))
+
+	var out strings.Builder
+	if err := tmpl.Execute(&out, si); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if out.String() != "Foo [a b] default" {
+		t.Errorf("template output = %q", out.String())
+	}
+
+	// Without a MarkerPrefix the names keep their "+"
+	got, err = p.ParseDirectory(parse.Options{Path: filepath.Join(wd, "./_testdata/markers")})
+	if err != nil {
+		t.Fatalf("ParseDirectory() error = %v", err)
+	}
+
+	si = got.Packages["github.com/gocloud9/gen-tool/pkg/parse/_testdata/markers"].Structs["Config"]
+
+	cfg = markerConfig{}
+	if err := parse.DecodeMarkerList(si.MarkerList, &cfg); err != nil {
+		t.Fatalf("DecodeMarkerList() error = %v", err)
+	}
+	if diff := cmp.Diff(want, cfg); diff != "" {
+		t.Errorf("default prefix list mismatch (-want +got):\n%s", diff)
+	}
+
+	cfg = markerConfig{}
+	if err := parse.DecodeMarkers(si.Markers, &cfg); err != nil {
+		t.Fatalf("DecodeMarkers() error = %v", err)
+	}
+	// The map keeps the last of the repeated +validate:enum markers
+	want.Enum = []string{"b"}
+	if diff := cmp.Diff(want, cfg); diff != "" {
+		t.Errorf("default prefix map mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,118 @@
+package parse
+
+import (
+	"errors"
+	"fmt"
+	"golang.org/x/tools/go/packages"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrPackageErrors is returned by ParseDirectory when packages failed to list, parse
+// or type check and Options.ContinueOnError is not set
+var ErrPackageErrors = errors.New("packages contain errors")
+
+type DiagnosticSeverity string
+
+const (
+	SeverityError   DiagnosticSeverity = "error"
+	SeverityWarning DiagnosticSeverity = "warning"
+)
+
+type DiagnosticKind string
+
+const (
+	DiagnosticMarker DiagnosticKind = "marker"
+	DiagnosticList   DiagnosticKind = "list"
+	DiagnosticParse  DiagnosticKind = "parse"
+	DiagnosticType   DiagnosticKind = "type"
+)
+
+type Diagnostic struct {
+	Severity DiagnosticSeverity
+	Kind     DiagnosticKind
+	Position Position
+	Package  string
+	Message  string
+}
+
+// String returns the diagnostic in the file:line:col: message form used by the go tool
+func (d Diagnostic) String() string {
+	if !d.Position.IsValid() {
+		return fmt.Sprintf("%s: %s", d.Package, d.Message)
+	}
+
+	return fmt.Sprintf("%s: %s", d.Position, d.Message)
+}
+
+func sortDiagnostics(diagnostics []Diagnostic) {
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		a, b := diagnostics[i], diagnostics[j]
+		if a.Package != b.Package {
+			return a.Package < b.Package
+		}
+		if a.Position.Filename != b.Position.Filename {
+			return a.Position.Filename < b.Position.Filename
+		}
+		if a.Position.Line != b.Position.Line {
+			return a.Position.Line < b.Position.Line
+		}
+		if a.Position.Column != b.Position.Column {
+			return a.Position.Column < b.Position.Column
+		}
+
+		return a.Position.Offset < b.Position.Offset
+	})
+}
+
+func packageDiagnostics(pkg *packages.Package) []Diagnostic {
+	diagnostics := []Diagnostic{}
+
+	for _, e := range pkg.Errors {
+		kind := DiagnosticList
+		switch e.Kind {
+		case packages.ParseError:
+			kind = DiagnosticParse
+		case packages.TypeError:
+			kind = DiagnosticType
+		}
+
+		diagnostics = append(diagnostics, Diagnostic{
+			Severity: SeverityError,
+			Kind:     kind,
+			Position: errorPosition(e.Pos),
+			Package:  pkg.PkgPath,
+			Message:  e.Msg,
+		})
+	}
+
+	return diagnostics
+}
+
+// errorPosition parses the "file:line:col" or "file:line" position of a packages.Error
+func errorPosition(pos string) Position {
+	p := Position{Filename: pos}
+	if pos == "" || pos == "-" {
+		return Position{}
+	}
+
+	for _, field := range []*int{&p.Column, &p.Line} {
+		i := strings.LastIndex(p.Filename, ":")
+		if i < 0 {
+			break
+		}
+		n, err := strconv.Atoi(p.Filename[i+1:])
+		if err != nil {
+			break
+		}
+		*field = n
+		p.Filename = p.Filename[:i]
+	}
+	// A single number is the line
+	if p.Line == 0 {
+		p.Line, p.Column = p.Column, 0
+	}
+
+	return p
+}
@@ -0,0 +1,354 @@
+package parse
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// The graph encoding is JSON keeping the identity of shared pointers: a struct
+// reached through several pointers is written once, with "$id": n, and the other
+// occurrences are {"$ref": n}. Embedded fields are written under their type name
+// and zero fields are left out. Maps are written in key order and read back in
+// the same order, so a "$ref" always follows its "$id".
+
+type graphKey struct {
+	typ  reflect.Type
+	addr uintptr
+}
+
+// graphDecoded is implemented by types restoring unexported state once read
+type graphDecoded interface {
+	afterGraphDecode()
+}
+
+func marshalGraph(v any) ([]byte, error) {
+	e := &graphEncoder{
+		counts: map[graphKey]int{},
+		ids:    map[graphKey]int{},
+	}
+	rv := reflect.ValueOf(v)
+	e.count(rv)
+	if err := e.encode(rv); err != nil {
+		return nil, err
+	}
+
+	return e.buf.Bytes(), nil
+}
+
+func unmarshalGraph(data []byte, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("unmarshal graph: want a non nil pointer, got %T", v)
+	}
+
+	d := &graphDecoder{pointers: map[int]reflect.Value{}}
+	if rv.Elem().Kind() != reflect.Struct {
+		return d.decode(data, rv.Elem())
+	}
+
+	// The root is decoded in place, it may be referenced from inside
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	return d.decodePointer(fields, rv)
+}
+
+type graphEncoder struct {
+	buf    bytes.Buffer
+	counts map[graphKey]int
+	ids    map[graphKey]int
+}
+
+func (e *graphEncoder) count(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return
+		}
+		key := graphKey{typ: v.Type(), addr: v.Pointer()}
+		e.counts[key]++
+		if e.counts[key] == 1 {
+			e.count(v.Elem())
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				e.count(v.Field(i))
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			e.count(v.Index(i))
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			e.count(iter.Value())
+		}
+	}
+}
+
+func (e *graphEncoder) encode(v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			e.buf.WriteString("null")
+			return nil
+		}
+		if v.Elem().Kind() != reflect.Struct {
+			return e.encode(v.Elem())
+		}
+
+		key := graphKey{typ: v.Type(), addr: v.Pointer()}
+		if id, ok := e.ids[key]; ok {
+			fmt.Fprintf(&e.buf, `{"$ref":%d}`, id)
+			return nil
+		}
+		id := 0
+		if e.counts[key] > 1 {
+			id = len(e.ids) + 1
+			e.ids[key] = id
+		}
+
+		return e.encodeStruct(v.Elem(), id)
+	case reflect.Struct:
+		return e.encodeStruct(v, 0)
+	case reflect.Slice:
+		if v.IsNil() {
+			e.buf.WriteString("null")
+			return nil
+		}
+		fallthrough
+	case reflect.Array:
+		e.buf.WriteByte('[')
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				e.buf.WriteByte(',')
+			}
+			if err := e.encode(v.Index(i)); err != nil {
+				return err
+			}
+		}
+		e.buf.WriteByte(']')
+	case reflect.Map:
+		if v.IsNil() {
+			e.buf.WriteString("null")
+			return nil
+		}
+		if v.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("marshal graph: unsupported map key %s", v.Type().Key())
+		}
+
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+
+		e.buf.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				e.buf.WriteByte(',')
+			}
+			e.writeString(key.String())
+			e.buf.WriteByte(':')
+			if err := e.encode(v.MapIndex(key)); err != nil {
+				return err
+			}
+		}
+		e.buf.WriteByte('}')
+	case reflect.Float32, reflect.Float64:
+		// JSON has no infinities, they are written as strings
+		if f := v.Float(); math.IsInf(f, 0) || math.IsNaN(f) {
+			e.writeString(strconv.FormatFloat(f, 'g', -1, 64))
+			return nil
+		}
+		fallthrough
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		data, err := json.Marshal(v.Interface())
+		if err != nil {
+			return err
+		}
+		e.buf.Write(data)
+	default:
+		return fmt.Errorf("marshal graph: unsupported type %s", v.Type())
+	}
+
+	return nil
+}
+
+func (e *graphEncoder) encodeStruct(v reflect.Value, id int) error {
+	e.buf.WriteByte('{')
+	first := true
+	if id > 0 {
+		fmt.Fprintf(&e.buf, `"$id":%d`, id)
+		first = false
+	}
+
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if !field.IsExported() || v.Field(i).IsZero() {
+			continue
+		}
+
+		if !first {
+			e.buf.WriteByte(',')
+		}
+		first = false
+		e.writeString(field.Name)
+		e.buf.WriteByte(':')
+		if err := e.encode(v.Field(i)); err != nil {
+			return fmt.Errorf("%s.%s: %w", v.Type().Name(), field.Name, err)
+		}
+	}
+	e.buf.WriteByte('}')
+
+	return nil
+}
+
+func (e *graphEncoder) writeString(s string) {
+	data, _ := json.Marshal(s)
+	e.buf.Write(data)
+}
+
+type graphDecoder struct {
+	pointers map[int]reflect.Value
+}
+
+func (d *graphDecoder) decode(data json.RawMessage, v reflect.Value) error {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		v.SetZero()
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.Type().Elem().Kind() != reflect.Struct {
+			p := reflect.New(v.Type().Elem())
+			if err := d.decode(data, p.Elem()); err != nil {
+				return err
+			}
+			v.Set(p)
+			return nil
+		}
+
+		fields := map[string]json.RawMessage{}
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return err
+		}
+		if ref, ok := fields["$ref"]; ok {
+			id, err := strconv.Atoi(string(ref))
+			if err != nil {
+				return fmt.Errorf("invalid $ref %s", ref)
+			}
+			p, ok := d.pointers[id]
+			if !ok || p.Type() != v.Type() {
+				return fmt.Errorf("$ref %d does not point to a %s", id, v.Type())
+			}
+			v.Set(p)
+			return nil
+		}
+
+		p := reflect.New(v.Type().Elem())
+		if err := d.decodePointer(fields, p); err != nil {
+			return err
+		}
+		v.Set(p)
+	case reflect.Struct:
+		fields := map[string]json.RawMessage{}
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return err
+		}
+		return d.decodeStruct(fields, v)
+	case reflect.Slice, reflect.Array:
+		items := []json.RawMessage{}
+		if err := json.Unmarshal(data, &items); err != nil {
+			return err
+		}
+		if v.Kind() == reflect.Slice {
+			v.Set(reflect.MakeSlice(v.Type(), len(items), len(items)))
+		} else if len(items) != v.Len() {
+			return fmt.Errorf("want %d items for %s, got %d", v.Len(), v.Type(), len(items))
+		}
+		for i, item := range items {
+			if err := d.decode(item, v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		items := map[string]json.RawMessage{}
+		if err := json.Unmarshal(data, &items); err != nil {
+			return err
+		}
+		keys := make([]string, 0, len(items))
+		for key := range items {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		m := reflect.MakeMapWithSize(v.Type(), len(items))
+		for _, key := range keys {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := d.decode(items[key], elem); err != nil {
+				return err
+			}
+			m.SetMapIndex(reflect.ValueOf(key).Convert(v.Type().Key()), elem)
+		}
+		v.Set(m)
+	case reflect.Float32, reflect.Float64:
+		var s string
+		if json.Unmarshal(data, &s) == nil {
+			f, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return err
+			}
+			v.SetFloat(f)
+			return nil
+		}
+		fallthrough
+	default:
+		return json.Unmarshal(data, v.Addr().Interface())
+	}
+
+	return nil
+}
+
+// decodePointer fills the struct p points to, registering p under its "$id" first
+func (d *graphDecoder) decodePointer(fields map[string]json.RawMessage, p reflect.Value) error {
+	if raw, ok := fields["$id"]; ok {
+		id, err := strconv.Atoi(string(raw))
+		if err != nil {
+			return fmt.Errorf("invalid $id %s", raw)
+		}
+		d.pointers[id] = p
+	}
+	if err := d.decodeStruct(fields, p.Elem()); err != nil {
+		return err
+	}
+	if decoded, ok := p.Interface().(graphDecoded); ok {
+		decoded.afterGraphDecode()
+	}
+
+	return nil
+}
+
+func (d *graphDecoder) decodeStruct(fields map[string]json.RawMessage, v reflect.Value) error {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		data, ok := fields[field.Name]
+		if !ok || !field.IsExported() {
+			continue
+		}
+		if err := d.decode(data, v.Field(i)); err != nil {
+			return fmt.Errorf("%s.%s: %w", v.Type().Name(), field.Name, err)
+		}
+	}
+
+	return nil
+}
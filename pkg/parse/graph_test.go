@@ -0,0 +1,195 @@
+package parse
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type GraphLeaf struct {
+	Value string
+}
+
+type graphNode struct {
+	Name     string
+	Next     *graphNode
+	Children []*graphNode
+}
+
+type graphHolder struct {
+	A, B   *GraphLeaf
+	List   []*GraphLeaf
+	Map    map[string]*GraphLeaf
+	Single *GraphLeaf
+
+	NilList   []string
+	EmptyList []string
+	NilMap    map[string]int
+	EmptyMap  map[string]int
+
+	*GraphLeaf
+	Floats []float64
+
+	hidden string
+}
+
+func roundTrip[T any](t *testing.T, v *T) (*T, []byte) {
+	t.Helper()
+
+	data, err := marshalGraph(v)
+	if err != nil {
+		t.Fatalf("marshalGraph() error = %v", err)
+	}
+	got := new(T)
+	if err := unmarshalGraph(data, got); err != nil {
+		t.Fatalf("unmarshalGraph() error = %v\n%s", err, data)
+	}
+
+	return got, data
+}
+
+func TestGraph_Cycles(t *testing.T) {
+	a := &graphNode{Name: "a"}
+	b := &graphNode{Name: "b", Next: a}
+	a.Next = b
+	a.Children = []*graphNode{a, b}
+
+	got, _ := roundTrip(t, a)
+	if got.Name != "a" || got.Next.Name != "b" {
+		t.Fatalf("names not restored, got %q and %q", got.Name, got.Next.Name)
+	}
+	if got.Next.Next != got {
+		t.Errorf("cycle a -> b -> a not restored")
+	}
+	if len(got.Children) != 2 || got.Children[0] != got || got.Children[1] != got.Next {
+		t.Errorf("children do not point to the nodes of the cycle")
+	}
+}
+
+func TestGraph_Values(t *testing.T) {
+	shared := &GraphLeaf{Value: "shared"}
+	v := &graphHolder{
+		A:         shared,
+		B:         shared,
+		List:      []*GraphLeaf{shared, nil},
+		Map:       map[string]*GraphLeaf{"z": shared, "a": shared},
+		Single:    &GraphLeaf{Value: "single"},
+		EmptyList: []string{},
+		EmptyMap:  map[string]int{},
+		GraphLeaf: &GraphLeaf{Value: "embedded"},
+		Floats:    []float64{1.5, math.Inf(1), math.Inf(-1)},
+		hidden:    "hidden",
+	}
+
+	got, data := roundTrip(t, v)
+
+	if got.A != got.B || got.List[0] != got.A || got.Map["a"] != got.A || got.Map["z"] != got.A {
+		t.Errorf("shared pointer not restored shared")
+	}
+	if got.List[1] != nil {
+		t.Errorf("nil item decoded as %+v", got.List[1])
+	}
+	if bytes.Count(data, []byte(`"$id"`)) != 1 || bytes.Count(data, []byte(`"$ref"`)) != 4 {
+		t.Errorf("want one $id and four $ref, got\n%s", data)
+	}
+	if got.NilList != nil || got.NilMap != nil {
+		t.Errorf("nil slice and map decoded as %v and %v", got.NilList, got.NilMap)
+	}
+	if got.EmptyList == nil || got.EmptyMap == nil {
+		t.Errorf("empty slice and map decoded as nil")
+	}
+	if !bytes.Contains(data, []byte(`"GraphLeaf":{"Value":"embedded"}`)) {
+		t.Errorf("embedded field not written under its type name\n%s", data)
+	}
+	if strings.Contains(string(data), "hidden") || got.hidden != "" {
+		t.Errorf("unexported field written")
+	}
+
+	opts := cmp.Options{cmp.AllowUnexported(graphHolder{}), cmp.Comparer(func(a, b float64) bool {
+		return a == b || (math.IsNaN(a) && math.IsNaN(b))
+	})}
+	want := *v
+	want.hidden = ""
+	if diff := cmp.Diff(&want, got, opts); diff != "" {
+		t.Errorf("round trip mismatch (-want +got):\n%s", diff)
+	}
+
+	again, err := marshalGraph(got)
+	if err != nil {
+		t.Fatalf("marshalGraph() error = %v", err)
+	}
+	if !bytes.Equal(data, again) {
+		t.Errorf("marshaling the decoded value differs:\n%s\n%s", data, again)
+	}
+}
+
+func TestGraph_NaN(t *testing.T) {
+	got, _ := roundTrip(t, &graphHolder{Floats: []float64{math.NaN()}})
+	if len(got.Floats) != 1 || !math.IsNaN(got.Floats[0]) {
+		t.Errorf("NaN decoded as %v", got.Floats)
+	}
+}
+
+func TestGraph_MarkerErr(t *testing.T) {
+	markers := &struct{ List []*Marker }{
+		List: []*Marker{parseMarker("max={1"), parseMarker("max=1")},
+	}
+
+	got, _ := roundTrip(t, markers)
+	if got.List[0].err == nil || got.List[0].err.Error() != markers.List[0].err.Error() {
+		t.Errorf("malformed marker error = %v, want %v", got.List[0].err, markers.List[0].err)
+	}
+	if got.List[1].err != nil {
+		t.Errorf("marker error = %v, want none", got.List[1].err)
+	}
+}
+
+func TestGraph_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		v    any
+	}{
+		{name: "interface field", v: &struct{ Value any }{Value: 1}},
+		{name: "non string map key", v: &struct{ Map map[int]string }{Map: map[int]string{1: "a"}}},
+		{name: "func field", v: &struct{ Fn func() }{Fn: func() {}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := marshalGraph(tt.v); err == nil {
+				t.Errorf("marshalGraph() should fail")
+			}
+		})
+	}
+
+	decodeTests := []struct {
+		name string
+		data string
+	}{
+		{name: "dangling reference", data: `{"A":{"$ref":1}}`},
+		{name: "invalid id", data: `{"A":{"$id":"x"}}`},
+		{name: "wrong kind", data: `{"List":{}}`},
+		{name: "not a string", data: `{"GraphLeaf":{"Value":1}}`},
+	}
+	for _, tt := range decodeTests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := unmarshalGraph([]byte(tt.data), &graphHolder{}); err == nil {
+				t.Errorf("unmarshalGraph() should fail")
+			}
+		})
+	}
+
+	mixed := &struct {
+		Leaf *GraphLeaf
+		Node *graphNode
+	}{}
+	if err := unmarshalGraph([]byte(`{"Leaf":{"$id":1,"Value":"a"},"Node":{"$ref":1}}`), mixed); err == nil {
+		t.Errorf("unmarshalGraph() of a reference of another type should fail")
+	}
+
+	if err := unmarshalGraph([]byte(`{}`), graphHolder{}); err == nil {
+		t.Errorf("unmarshalGraph() into a non pointer should fail")
+	}
+}
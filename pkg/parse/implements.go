@@ -0,0 +1,149 @@
+package parse
+
+import (
+	"go/types"
+	"golang.org/x/tools/go/packages"
+	"sort"
+	"text/template"
+)
+
+// ImplementationInfo links an interface to a struct or defined type implementing it.
+// Pointer is set when only the pointer type implements the interface, so
+// `var _ I = (*T)(nil)` always compiles and `var _ I = T{}` only when Pointer is false.
+type ImplementationInfo struct {
+	Interface        *InterfaceInfo
+	InterfacePackage string
+
+	TypeName    string
+	TypePackage string
+	Struct      *StructInfo
+	DefinedType *DefinedTypeInfo
+	Pointer     bool
+}
+
+// ImplementersOf returns the implementations of the interface sorted by type
+func (r *Results) ImplementersOf(ii *InterfaceInfo) []*ImplementationInfo {
+	for path, pi := range r.Packages {
+		if pi.Interfaces[ii.Name] == ii {
+			return r.Implementers[qualifiedName(path, ii.Name)]
+		}
+	}
+
+	return []*ImplementationInfo{}
+}
+
+// InterfacesOf returns the interfaces implemented by a *StructInfo or *DefinedTypeInfo sorted by interface
+func (r *Results) InterfacesOf(info any) []*ImplementationInfo {
+	for path, pi := range r.Packages {
+		switch info := info.(type) {
+		case *StructInfo:
+			if pi.Structs[info.Name] == info {
+				return r.Implements[qualifiedName(path, info.Name)]
+			}
+		case *DefinedTypeInfo:
+			if pi.DefinedTypes[info.Name] == info {
+				return r.Implements[qualifiedName(path, info.Name)]
+			}
+		}
+	}
+
+	return []*ImplementationInfo{}
+}
+
+// ImplementationFuncs returns the template funcs "implementers", taking an
+// interface, and "implements", taking a struct or defined type
+func ImplementationFuncs(r *Results) template.FuncMap {
+	return template.FuncMap{
+		"implementers": r.ImplementersOf,
+		"implements":   r.InterfacesOf,
+	}
+}
+
+func qualifiedName(pkgPath, name string) string {
+	return pkgPath + "." + name
+}
+
+// collectImplementations checks every non generic struct and defined type of the
+// parsed packages against their non empty, non generic interfaces. Interfaces
+// with type constraints only serve as constraints and are skipped.
+func collectImplementations(results *Results, pkgs []*packages.Package) {
+	results.Implementers = map[string][]*ImplementationInfo{}
+	results.Implements = map[string][]*ImplementationInfo{}
+
+	type declared struct {
+		pkg   string
+		named *types.Named
+	}
+	interfaces := []declared{}
+	concretes := []declared{}
+
+	for _, pkg := range pkgs {
+		pi, ok := results.Packages[pkg.PkgPath]
+		if !ok || pkg.Types == nil {
+			continue
+		}
+
+		for _, name := range pkg.Types.Scope().Names() {
+			obj, ok := pkg.Types.Scope().Lookup(name).(*types.TypeName)
+			if !ok || obj.IsAlias() {
+				continue
+			}
+			named, ok := obj.Type().(*types.Named)
+			if !ok || named.TypeParams().Len() > 0 {
+				continue
+			}
+
+			if iface, ok := named.Underlying().(*types.Interface); ok {
+				if _, parsed := pi.Interfaces[name]; parsed && iface.IsMethodSet() && iface.NumMethods() > 0 {
+					interfaces = append(interfaces, declared{pkg: pkg.PkgPath, named: named})
+				}
+				continue
+			}
+			_, isStruct := pi.Structs[name]
+			_, isDefined := pi.DefinedTypes[name]
+			if isStruct || isDefined {
+				concretes = append(concretes, declared{pkg: pkg.PkgPath, named: named})
+			}
+		}
+	}
+
+	for _, i := range interfaces {
+		iface := i.named.Underlying().(*types.Interface)
+		for _, c := range concretes {
+			pointer := false
+			if !types.Implements(c.named, iface) {
+				if !types.Implements(types.NewPointer(c.named), iface) {
+					continue
+				}
+				pointer = true
+			}
+
+			name := c.named.Obj().Name()
+			impl := &ImplementationInfo{
+				Interface:        results.Packages[i.pkg].Interfaces[i.named.Obj().Name()],
+				InterfacePackage: i.pkg,
+				TypeName:         name,
+				TypePackage:      c.pkg,
+				Struct:           results.Packages[c.pkg].Structs[name],
+				DefinedType:      results.Packages[c.pkg].DefinedTypes[name],
+				Pointer:          pointer,
+			}
+
+			interfaceKey := qualifiedName(i.pkg, i.named.Obj().Name())
+			typeKey := qualifiedName(c.pkg, name)
+			results.Implementers[interfaceKey] = append(results.Implementers[interfaceKey], impl)
+			results.Implements[typeKey] = append(results.Implements[typeKey], impl)
+		}
+	}
+
+	for _, impls := range results.Implementers {
+		sort.Slice(impls, func(a, b int) bool {
+			return qualifiedName(impls[a].TypePackage, impls[a].TypeName) < qualifiedName(impls[b].TypePackage, impls[b].TypeName)
+		})
+	}
+	for _, impls := range results.Implements {
+		sort.Slice(impls, func(a, b int) bool {
+			return qualifiedName(impls[a].InterfacePackage, impls[a].Interface.Name) < qualifiedName(impls[b].InterfacePackage, impls[b].Interface.Name)
+		})
+	}
+}
@@ -0,0 +1,399 @@
+package parse
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type MarkerValueKind string
+
+const (
+	MarkerValueString MarkerValueKind = "string"
+	MarkerValueInt    MarkerValueKind = "int"
+	MarkerValueBool   MarkerValueKind = "bool"
+	MarkerValueList   MarkerValueKind = "list"
+	MarkerValueMap    MarkerValueKind = "map"
+)
+
+// Marker is a comment marker such as "+gen:builder:name=Foo,skip={A,B},max=10".
+// Path holds the colon separated segments of the name. A marker with several
+// "key=value" arguments takes the first key from the last name segment, so the
+// example above is named "gen:builder" with the arguments name, skip and max.
+// The value of a marker with a single argument, like "+validate:enum=a", is
+// stored under the "" key.
+type Marker struct {
+	Name     string
+	Path     []string
+	Raw      string
+	Position Position
+	Args     map[string]*MarkerValue
+	ArgNames []string
+
+	err error
+}
+
+// MarkerValue is a typed marker argument. Quoted values are strings, {a,b} and
+// a;b are lists, {k:v} are maps and true, false and integers are parsed as such.
+// Anything else is a bare string.
+type MarkerValue struct {
+	Kind        MarkerValueKind
+	Raw         string
+	StringValue string
+	IntValue    int64
+	BoolValue   bool
+	List        []*MarkerValue
+	Map         map[string]*MarkerValue
+}
+
+// Value returns the argument as a string, int64, bool, []any or map[string]any
+func (v *MarkerValue) Value() any {
+	switch v.Kind {
+	case MarkerValueInt:
+		return v.IntValue
+	case MarkerValueBool:
+		return v.BoolValue
+	case MarkerValueList:
+		values := make([]any, 0, len(v.List))
+		for _, item := range v.List {
+			values = append(values, item.Value())
+		}
+		return values
+	case MarkerValueMap:
+		values := make(map[string]any, len(v.Map))
+		for key, item := range v.Map {
+			values[key] = item.Value()
+		}
+		return values
+	default:
+		return v.StringValue
+	}
+}
+
+type markerLine struct {
+	text string
+	pos  token.Pos
+	end  token.Pos
+}
+
+// markerLines returns the marker text of the comment lines starting with the prefix, without the prefix.
+// Without a prefix every comment is a single marker.
+func markerLines(cg *ast.CommentGroup, prefix string) []markerLine {
+	if cg == nil {
+		return nil
+	}
+
+	lines := []markerLine{}
+	for _, c := range cg.List {
+		if prefix == "" {
+			txt := strings.TrimSpace(strings.TrimPrefix(c.Text, "//"))
+			txt = strings.TrimSpace(strings.TrimPrefix(txt, "/*"))
+			txt = strings.TrimSpace(strings.TrimSuffix(txt, "*/"))
+			lines = append(lines, markerLine{text: txt, pos: c.Pos(), end: c.End()})
+			continue
+		}
+
+		body := c.Text[2:]
+		if strings.HasPrefix(c.Text, "/*") {
+			body = strings.TrimSuffix(body, "*/")
+		}
+
+		offset := 2
+		for _, line := range strings.Split(body, "\n") {
+			trimmed := strings.TrimSpace(line)
+			if strings.HasPrefix(trimmed, prefix) {
+				start := c.Pos() + token.Pos(offset+strings.Index(line, trimmed))
+				lines = append(lines, markerLine{
+					text: strings.TrimPrefix(trimmed, prefix),
+					pos:  start,
+					end:  start + token.Pos(len(trimmed)),
+				})
+			}
+			offset += len(line) + 1
+		}
+	}
+
+	return lines
+}
+
+func markerValues(cg *ast.CommentGroup, prefix string) map[string]string {
+	values := map[string]string{}
+
+	for _, line := range markerLines(cg, prefix) {
+		parts := strings.SplitN(line.text, "=", 2)
+		if len(parts) == 2 {
+			values[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+		} else {
+			values[strings.TrimSpace(parts[0])] = ""
+		}
+	}
+
+	return values
+}
+
+func markerList(cg *ast.CommentGroup, fileCache fileCachedData) []*Marker {
+	markers := []*Marker{}
+
+	for _, line := range markerLines(cg, fileCache.markerPrefix) {
+		m := parseMarker(line.text)
+		m.Position = fileCache.position(line.pos, line.end)
+		markers = append(markers, m)
+	}
+
+	return markers
+}
+
+// docText returns the comment text without comment markers, directives and marker lines.
+// Without a prefix every line is a marker and there is no doc text.
+func docText(cg *ast.CommentGroup, prefix string) string {
+	if cg == nil || prefix == "" {
+		return ""
+	}
+
+	lines := []string{}
+	for _, line := range strings.Split(cg.Text(), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), prefix) {
+			continue
+		}
+		if line == "" && (len(lines) == 0 || lines[len(lines)-1] == "") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func parseMarker(text string) *Marker {
+	m := &Marker{
+		Raw:      text,
+		Args:     map[string]*MarkerValue{},
+		ArgNames: []string{},
+	}
+
+	name, rest, hasArgs := strings.Cut(text, "=")
+	m.Name = strings.TrimSpace(name)
+	m.Path = strings.Split(m.Name, ":")
+	if !hasArgs {
+		return m
+	}
+
+	items, err := splitMarkerText(rest, ',')
+	if err != nil {
+		m.err = err
+		return m
+	}
+
+	if len(m.Path) < 2 || len(items) < 2 || !namedMarkerArgs(items[1:]) {
+		m.addArg("", rest)
+		return m
+	}
+
+	m.Path = m.Path[:len(m.Path)-1]
+	m.Name = strings.Join(m.Path, ":")
+	items[0] = name[len(m.Name)+1:] + "=" + items[0]
+	for _, item := range items {
+		argName, value, _ := strings.Cut(item, "=")
+		m.addArg(strings.TrimSpace(argName), value)
+	}
+
+	return m
+}
+
+func (m *Marker) addArg(name string, raw string) {
+	if _, ok := m.Args[name]; ok {
+		m.setErr(fmt.Errorf("repeated argument %q", name))
+		return
+	}
+
+	v, err := parseMarkerValue(raw)
+	if err != nil {
+		m.setErr(err)
+		return
+	}
+
+	m.Args[name] = v
+	m.ArgNames = append(m.ArgNames, name)
+}
+
+// afterGraphDecode restores the parse error of a marker read back from JSON
+func (m *Marker) afterGraphDecode() {
+	m.err = parseMarker(m.Raw).err
+}
+
+func (m *Marker) setErr(err error) {
+	if m.err == nil {
+		m.err = err
+	}
+}
+
+func namedMarkerArgs(items []string) bool {
+	for _, item := range items {
+		name, _, ok := strings.Cut(item, "=")
+		if !ok || !isMarkerArgName(strings.TrimSpace(name)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isMarkerArgName reports whether the name is shaped like an identifier, keywords such as "type" included
+func isMarkerArgName(name string) bool {
+	if name == "" {
+		return false
+	}
+
+	for i, c := range name {
+		if !unicode.IsLetter(c) && c != '_' && (i == 0 || !unicode.IsDigit(c)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func parseMarkerValue(raw string) (*MarkerValue, error) {
+	s := strings.TrimSpace(raw)
+	v := &MarkerValue{Raw: s, Kind: MarkerValueString}
+
+	switch {
+	case strings.HasPrefix(s, `"`) || strings.HasPrefix(s, "`"):
+		str, err := strconv.Unquote(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid string %s", s)
+		}
+		v.StringValue = str
+	case strings.HasPrefix(s, "{"):
+		if !strings.HasSuffix(s, "}") {
+			return nil, fmt.Errorf("unterminated { in %s", s)
+		}
+		items, err := splitMarkerText(s[1:len(s)-1], ',')
+		if err != nil {
+			return nil, err
+		}
+		if err := setMarkerCollection(v, items); err != nil {
+			return nil, err
+		}
+	default:
+		items, err := splitMarkerText(s, ';')
+		if err != nil {
+			return nil, err
+		}
+		if len(items) > 1 {
+			return v, setMarkerCollection(v, items)
+		}
+
+		if s == "true" || s == "false" {
+			v.Kind = MarkerValueBool
+			v.BoolValue = s == "true"
+		} else if i, err := strconv.ParseInt(s, 0, 64); err == nil {
+			v.Kind = MarkerValueInt
+			v.IntValue = i
+		} else {
+			v.StringValue = s
+		}
+	}
+
+	return v, nil
+}
+
+// setMarkerCollection fills a map when every item is "key:value" and a list otherwise
+func setMarkerCollection(v *MarkerValue, items []string) error {
+	if len(items) == 1 && strings.TrimSpace(items[0]) == "" {
+		items = nil
+	}
+
+	isMap := len(items) > 0
+	pairs := make([][]string, 0, len(items))
+	for _, item := range items {
+		pair, err := splitMarkerText(item, ':')
+		if err != nil {
+			return err
+		}
+		if len(pair) != 2 {
+			isMap = false
+		}
+		pairs = append(pairs, pair)
+	}
+
+	if !isMap {
+		v.Kind = MarkerValueList
+		v.List = []*MarkerValue{}
+		for _, item := range items {
+			iv, err := parseMarkerValue(item)
+			if err != nil {
+				return err
+			}
+			v.List = append(v.List, iv)
+		}
+
+		return nil
+	}
+
+	v.Kind = MarkerValueMap
+	v.Map = map[string]*MarkerValue{}
+	for _, pair := range pairs {
+		key := strings.TrimSpace(pair[0])
+		if unquoted, err := strconv.Unquote(key); err == nil {
+			key = unquoted
+		}
+		iv, err := parseMarkerValue(pair[1])
+		if err != nil {
+			return err
+		}
+		v.Map[key] = iv
+	}
+
+	return nil
+}
+
+// splitMarkerText splits on the separator outside of quotes and braces
+func splitMarkerText(s string, sep byte) ([]string, error) {
+	items := []string{}
+	depth := 0
+	var quote byte
+	start := 0
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '`':
+			quote = c
+		case c == '{':
+			depth++
+		case c == '}':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("unexpected } in %s", s)
+			}
+		case c == sep && depth == 0:
+			items = append(items, s[start:i])
+			start = i + 1
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated string in %s", s)
+	}
+	if depth > 0 {
+		return nil, fmt.Errorf("unterminated { in %s", s)
+	}
+
+	return append(items, s[start:]), nil
+}
@@ -0,0 +1,83 @@
+package parse
+
+import (
+	"go/types"
+)
+
+// attachMethods adds the methods to their receiver types once every file of the package is read,
+// then fills the value and pointer method sets, promoted methods included. Methods of receivers
+// that were not parsed go to UnattachedMethods.
+func attachMethods(pi *PackageInfo, fileCache fileCachedData) {
+	unattached := map[string]*FuncInfo{}
+	for receiver, fis := range fileCache.methods {
+		receiver = receiverTypeName(receiver, fileCache)
+		for _, fi := range fis {
+			if si, ok := pi.Structs[receiver]; ok {
+				si.Methods[fi.Name] = fi
+			} else if dti, ok := pi.DefinedTypes[receiver]; ok {
+				dti.Methods[fi.Name] = fi
+			} else {
+				unattached[receiver+"."+fi.Name] = fi
+			}
+		}
+	}
+	if len(unattached) > 0 {
+		pi.UnattachedMethods = sortedByPosition(unattached, func(fi *FuncInfo) Position { return fi.Position })
+	}
+
+	for _, si := range pi.Structs {
+		si.ValueMethodSet, si.PointerMethodSet = methodSets(pi, si.Name, si.Methods, fileCache)
+	}
+	for _, dti := range pi.DefinedTypes {
+		dti.ValueMethodSet, dti.PointerMethodSet = methodSets(pi, dti.Name, dti.Methods, fileCache)
+	}
+}
+
+// receiverTypeName resolves a receiver declared with an alias to the name of the aliased type
+func receiverTypeName(name string, fileCache fileCachedData) string {
+	obj, ok := fileCache.types.Scope().Lookup(name).(*types.TypeName)
+	if !ok || !obj.IsAlias() {
+		return name
+	}
+	named, ok := types.Unalias(obj.Type()).(*types.Named)
+	if !ok || named.Obj().Pkg() != fileCache.types {
+		return name
+	}
+
+	return named.Obj().Name()
+}
+
+func methodSets(pi *PackageInfo, name string, methods map[string]*FuncInfo, fileCache fileCachedData) ([]*FuncInfo, []*FuncInfo) {
+	obj, ok := fileCache.types.Scope().Lookup(name).(*types.TypeName)
+	if !ok {
+		return []*FuncInfo{}, []*FuncInfo{}
+	}
+	named, ok := obj.Type().(*types.Named)
+	if !ok {
+		return []*FuncInfo{}, []*FuncInfo{}
+	}
+
+	return methodSetInfos(pi, named, types.NewMethodSet(named), methods, fileCache),
+		methodSetInfos(pi, named, types.NewMethodSet(types.NewPointer(named)), methods, fileCache)
+}
+
+// methodSetInfos returns the methods of the set sorted by name
+func methodSetInfos(pi *PackageInfo, named *types.Named, methodSet *types.MethodSet, methods map[string]*FuncInfo, fileCache fileCachedData) []*FuncInfo {
+	fis := []*FuncInfo{}
+
+	for i := 0; i < methodSet.Len(); i++ {
+		selection := methodSet.At(i)
+		method := selection.Obj().(*types.Func)
+		if len(selection.Index()) == 1 {
+			if fi, ok := methods[method.Name()]; ok {
+				fis = append(fis, fi)
+			}
+			continue
+		}
+
+		_, owner := selectionPath(named, selection.Index())
+		fis = append(fis, promotedMethodInfo(pi, owner, method, fileCache))
+	}
+
+	return fis
+}
@@ -0,0 +1,63 @@
+package parse
+
+import (
+	"sort"
+)
+
+// orderPackageInfo fills the declaration ordered lists from the maps keyed by name
+func orderPackageInfo(pi *PackageInfo) {
+	pi.StructList = sortedByPosition(pi.Structs, func(si *StructInfo) Position { return si.Position })
+	pi.ConstantList = sortedByPosition(pi.Constants, func(ci *ConstantInfo) Position { return ci.Position })
+	pi.FunctionList = sortedByPosition(pi.Functions, func(fi *FuncInfo) Position { return fi.Position })
+	pi.InterfaceList = sortedByPosition(pi.Interfaces, func(ii *InterfaceInfo) Position { return ii.Position })
+	pi.VarList = sortedByPosition(pi.Vars, func(vi *VarInfo) Position { return vi.Position })
+	pi.DefinedTypeList = sortedByPosition(pi.DefinedTypes, func(dti *DefinedTypeInfo) Position { return dti.Position })
+	pi.AliasList = sortedByPosition(pi.Aliases, func(ati *AliasTypeInfo) Position { return ati.Position })
+
+	for _, si := range pi.Structs {
+		orderStructInfo(si)
+	}
+	for _, ii := range pi.Interfaces {
+		orderInterfaceInfo(ii)
+	}
+	for _, dti := range pi.DefinedTypes {
+		dti.MethodList = sortedByPosition(dti.Methods, func(fi *FuncInfo) Position { return fi.Position })
+	}
+}
+
+func orderStructInfo(si *StructInfo) {
+	si.FieldList = sortedByPosition(si.Fields, func(fi *FieldInfo) Position { return fi.Position })
+	si.MethodList = sortedByPosition(si.Methods, func(fi *FuncInfo) Position { return fi.Position })
+	si.EmbeddedFieldList = sortedByPosition(si.EmbeddedFields, func(efi EmbeddedFieldInfo) Position { return efi.Position })
+}
+
+func orderInterfaceInfo(ii *InterfaceInfo) {
+	ii.MethodList = sortedByPosition(ii.Methods, func(fi *FuncInfo) Position { return fi.Position })
+	ii.EmbeddedTypeList = sortedByPosition(ii.EmbeddedTypes, func(eti *EmbeddedTypeInfo) Position { return eti.Position })
+}
+
+func sortedByPosition[T any](values map[string]T, position func(T) Position) []T {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := position(values[keys[i]]), position(values[keys[j]])
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		if a.Offset != b.Offset {
+			return a.Offset < b.Offset
+		}
+
+		return keys[i] < keys[j]
+	})
+
+	sorted := make([]T, 0, len(keys))
+	for _, key := range keys {
+		sorted = append(sorted, values[key])
+	}
+
+	return sorted
+}
@@ -4,15 +4,20 @@ import (
 	"fmt"
 	"go/ast"
 	"go/token"
+	"go/types"
 	"golang.org/x/tools/go/packages"
-	"log"
-	"reflect"
 	"regexp"
 	"strings"
 )
 
 type PackageInfo struct {
 	Name         string
+	Path         string
+	Dir          string
+	GoFiles      []string
+	Module       *ModuleInfo
+	Build        *BuildInfo
+	Doc          string
 	Structs      map[string]*StructInfo
 	Constants    map[string]*ConstantInfo
 	Functions    map[string]*FuncInfo
@@ -20,30 +25,95 @@ type PackageInfo struct {
 	Vars         map[string]*VarInfo
 	DefinedTypes map[string]*DefinedTypeInfo
 	Aliases      map[string]*AliasTypeInfo
+	Enums        map[string]*EnumInfo
+
+	StructList      []*StructInfo
+	ConstantList    []*ConstantInfo
+	FunctionList    []*FuncInfo
+	InterfaceList   []*InterfaceInfo
+	VarList         []*VarInfo
+	DefinedTypeList []*DefinedTypeInfo
+	AliasList       []*AliasTypeInfo
+	EnumList        []*EnumInfo
+
+	// UnattachedMethods are the methods whose receiver type was not parsed, e.g. its file was skipped
+	UnattachedMethods []*FuncInfo
+}
+
+type ModuleInfo struct {
+	Path      string
+	Version   string
+	GoVersion string
+	Main      bool
 }
 
 type DefinedTypeInfo struct {
-	Name    string
-	Markers map[string]string
+	Name       string
+	Position   Position
+	Markers    map[string]string
+	MarkerList []*Marker
+	Doc        string
+	TypeParams []*TypeParamInfo
+	Methods    map[string]*FuncInfo
 	*TypeInfo
+
+	MethodList       []*FuncInfo
+	ValueMethodSet   []*FuncInfo
+	PointerMethodSet []*FuncInfo
 }
 
 type AliasTypeInfo struct {
-	Name    string
-	Markers map[string]string
+	Name       string
+	Position   Position
+	Markers    map[string]string
+	MarkerList []*Marker
+	Doc        string
+	TypeParams []*TypeParamInfo
 	*TypeInfo
 }
 
+type TypeParamInfo struct {
+	Name       string
+	Constraint *TypeInfo
+}
+
+// ConstantInfo is a declared constant. Only ExactValue holds any value losslessly:
+// IntValue is set when IntExact, i.e. when the value fits an int64, and FloatValue
+// is the nearest float64.
 type ConstantInfo struct {
-	Name     string
-	TypeName string
-	Markers  map[string]string
-	Value    string
+	Name        string
+	Position    Position
+	TypeName    string
+	Markers     map[string]string
+	MarkerList  []*Marker
+	Doc         string
+	Value       string
+	Kind        ConstantKind
+	IsUntyped   bool
+	ExactValue  string
+	BoolValue   bool
+	StringValue string
+	IntValue    int64
+	IntExact    bool
+	FloatValue  float64
+}
+
+type EnumInfo struct {
+	Name       string
+	Position   Position
+	Markers    map[string]string
+	MarkerList []*Marker
+	Doc        string
+	Type       *DefinedTypeInfo
+	Values     []*ConstantInfo
 }
 
 type VarInfo struct {
-	Name    string
-	Markers map[string]string
+	Name       string
+	Position   Position
+	Markers    map[string]string
+	MarkerList []*Marker
+	Doc        string
 	*TypeInfo
 }
 
@@ -52,45 +122,88 @@ type ImportedTypeInfo struct {
 	ImportRaw           string
 	PackagePath         string
 	PackageDefaultAlias string
+	Alias               string
 }
 
 type TypeInfo struct {
 	TypeName         string
 	ExternalTypeName string
 	PackageName      string
+	PackagePath      string
 	IsPointer        bool
 	IsMap            bool
 	IsSlice          bool
 	IsStruct         bool
 	IsChan           bool
+	IsArray          bool
 	IsFunc           bool
 	IsInterface      bool
 	IsEllipsis       bool
 	IsImported       bool
 	IsType           bool
+	IsTypeParam      bool
+	IsGeneric        bool
+	IsUnion          bool
+	IsApproximate    bool
+	IsRecursive      bool
 	MapKey           *TypeInfo
 	MapValue         *TypeInfo
 	Slice            *TypeInfo
 	Chan             *TypeInfo
+	ChanDir          ChanDir
+	Array            *TypeInfo
+	ArrayLen         int64
+	Struct           *StructInfo
 	Func             *FuncDefInfo
 	Interface        *InterfaceInfo
 	Pointer          *TypeInfo
 	Ellipsis         *TypeInfo
 	ImportedType     *ImportedTypeInfo
 	TypeOf           *TypeInfo
+	TypeArgs         []*TypeInfo
+	Union            []*TypeInfo
+
+	// Declaration of the named type when its package is part of the Results
+	StructDecl      *StructInfo
+	InterfaceDecl   *InterfaceInfo
+	DefinedTypeDecl *DefinedTypeInfo
+	AliasDecl       *AliasTypeInfo
+	// Entry of the named type in Results.Types
+	Named *NamedTypeInfo
 }
+type ChanDir string
+
+const (
+	ChanBoth ChanDir = "both"
+	ChanSend ChanDir = "send"
+	ChanRecv ChanDir = "recv"
+)
+
 type FieldInfo struct {
-	Name    string
-	Markers map[string]string
-	Tags    map[string][]string
+	Name       string
+	Position   Position
+	Markers    map[string]string
+	MarkerList []*Marker
+	Doc        string
+	RawTag     string
+	Tags       map[string][]string
+	TagList    TagList
 	*TypeInfo
 }
 
 type InterfaceInfo struct {
 	Name          string
+	Position      Position
 	Markers       map[string]string
+	MarkerList    []*Marker
+	Doc           string
+	TypeParams    []*TypeParamInfo
 	Methods       map[string]*FuncInfo
 	EmbeddedTypes map[string]*EmbeddedTypeInfo
+	Constraints   []*TypeInfo
+
+	MethodList       []*FuncInfo
+	EmbeddedTypeList []*EmbeddedTypeInfo
 }
 
 type FuncDefInfo struct {
@@ -102,10 +215,17 @@ type FuncDefInfo struct {
 }
 
 type FuncInfo struct {
-	Name        string
-	Markers     map[string]string
-	HasReciver  bool
-	ReciverName string
+	Name              string
+	Position          Position
+	Markers           map[string]string
+	MarkerList        []*Marker
+	Doc               string
+	HasReciver        bool
+	ReciverName       string
+	ReceiverVarName   string
+	ReceiverType      *TypeInfo
+	IsPointerReceiver bool
+	TypeParams        []*TypeParamInfo
 	*FuncDefInfo
 }
 
@@ -121,28 +241,69 @@ type ResultInfo struct {
 }
 
 type EmbeddedFieldInfo struct {
-	Name     string
-	TypeName string
-	Markers  map[string]string
-	Tags     map[string][]string
+	Name       string
+	Position   Position
+	Markers    map[string]string
+	MarkerList []*Marker
+	Doc        string
+	RawTag     string
+	Tags       map[string][]string
+	TagList    TagList
+	*TypeInfo
 }
 
 type EmbeddedTypeInfo struct {
-	Name     string
-	TypeName string
-	Markers  map[string]string
+	Name       string
+	Position   Position
+	Markers    map[string]string
+	MarkerList []*Marker
+	Doc        string
+	*TypeInfo
+}
+
+// PromotedFieldInfo is a field reached through embedded fields, Path holds their names
+type PromotedFieldInfo struct {
+	*FieldInfo
+	Path []string
+}
+
+// PromotedMethodInfo is a method reached through embedded fields, Path holds their names
+type PromotedMethodInfo struct {
+	*FuncInfo
+	Path []string
 }
 
 type StructInfo struct {
 	Name           string
+	Position       Position
 	Markers        map[string]string
+	MarkerList     []*Marker
+	Doc            string
+	TypeParams     []*TypeParamInfo
 	Fields         map[string]*FieldInfo
 	Methods        map[string]*FuncInfo
 	EmbeddedFields map[string]EmbeddedFieldInfo
+
+	FieldList         []*FieldInfo
+	MethodList        []*FuncInfo
+	EmbeddedFieldList []EmbeddedFieldInfo
+	ValueMethodSet    []*FuncInfo
+	PointerMethodSet  []*FuncInfo
+
+	// Filled when Options.IncludePromoted is set
+	PromotedFields  []*PromotedFieldInfo
+	PromotedMethods []*PromotedMethodInfo
 }
 
 type Results struct {
-	Packages map[string]*PackageInfo
+	Packages    map[string]*PackageInfo
+	Diagnostics []Diagnostic
+	// Implementers maps "pkgpath.Interface" to the types implementing it,
+	// Implements maps "pkgpath.Type" to the interfaces it implements
+	Implementers map[string][]*ImplementationInfo
+	Implements   map[string][]*ImplementationInfo
+	// Types holds every named type met while parsing once, keyed "pkgpath.Name"
+	Types map[string]*NamedTypeInfo
 }
 
 type Parser struct {
@@ -152,156 +313,197 @@ func exprToTypeInfo(e ast.Expr, fileCache fileCachedData) *TypeInfo {
 	varInfo := &TypeInfo{}
 
 	switch expr := e.(type) {
-	case *ast.MapType:
-		varInfo.IsMap = true
-		varInfo.MapKey = exprToTypeInfo(expr.Key, fileCache)
-		varInfo.MapValue = exprToTypeInfo(expr.Value, fileCache)
-		varInfo.TypeName = fmt.Sprintf("map[%s]%s", varInfo.MapKey.TypeName, varInfo.MapValue.TypeName)
-		varInfo.ExternalTypeName = fmt.Sprintf("map[%s]%s", varInfo.MapKey.ExternalTypeName, varInfo.MapValue.ExternalTypeName)
-	case *ast.SliceExpr:
-		varInfo.IsSlice = true
-		varInfo.Slice = exprToTypeInfo(expr.X, fileCache)
-		varInfo.TypeName = "[]" + varInfo.Slice.TypeName
-		varInfo.ExternalTypeName = "[]" + varInfo.Slice.ExternalTypeName
-	case *ast.StarExpr:
-		varInfo.IsPointer = true
-		varInfo.Pointer = exprToTypeInfo(expr.X, fileCache)
-		varInfo.TypeName = "*" + varInfo.Pointer.TypeName
-		varInfo.ExternalTypeName = "*" + varInfo.Pointer.ExternalTypeName
-	case *ast.ArrayType:
-		varInfo.IsSlice = true
-		varInfo.Slice = exprToTypeInfo(expr.Elt, fileCache)
-		varInfo.TypeName = "[]" + varInfo.Slice.TypeName
-		varInfo.ExternalTypeName = "[]" + varInfo.Slice.ExternalTypeName
-	case *ast.ChanType:
-		varInfo.IsChan = true
-		varInfo.Chan = exprToTypeInfo(expr.Value, fileCache)
-		varInfo.TypeName = "chan " + varInfo.Chan.TypeName
-		varInfo.ExternalTypeName = "chan " + varInfo.Chan.ExternalTypeName
-	case *ast.StructType:
-		varInfo.IsStruct = true
-
-	case *ast.InterfaceType:
-		varInfo.IsInterface = true
 	case *ast.Ellipsis:
-		varInfo.IsEllipsis = true
-		varInfo.Ellipsis = exprToTypeInfo(expr.Elt, fileCache)
-		varInfo.TypeName = "..." + varInfo.Ellipsis.TypeName
-		varInfo.ExternalTypeName = "..." + varInfo.Ellipsis.ExternalTypeName
-	case *ast.FuncType:
-		varInfo.IsFunc = true
-		params := fieldListToParamInfoList(expr.Params, fileCache)
-		results := fieldListToResultInfoList(expr.Results, fileCache)
-
-		varInfo.TypeName = funcTypeNameFromParamsAndResults(params, results, false)
-		varInfo.ExternalTypeName = funcTypeNameFromParamsAndResults(params, results, true)
-
-		varInfo.Func = &FuncDefInfo{
-			IsVariadic: isVariadicFunc(params),
-			Params:     params,
-			Results:    results,
+		varInfo = ellipsisTypeInfo(exprToTypeInfo(expr.Elt, fileCache))
+	case *ast.ParenExpr:
+		varInfo = exprToTypeInfo(expr.X, fileCache)
+	case *ast.UnaryExpr:
+		varInfo = exprToTypeInfo(expr.X, fileCache)
+		if expr.Op == token.TILDE {
+			varInfo.IsApproximate = true
+			varInfo.TypeName = "~" + varInfo.TypeName
+			varInfo.ExternalTypeName = "~" + varInfo.ExternalTypeName
 		}
-
-	case *ast.SelectorExpr:
-		varInfo = exprToTypeInfo(expr.Sel, fileCache)
-		imported, ok := fileCache.imports[expr.X.(*ast.Ident).Name]
-		if ok {
-			packagePath := strings.ReplaceAll(imported.Path.Value, "\"", "")
-			parts := strings.Split(packagePath, "/")
-			varInfo.IsImported = true
-			varInfo.ImportedType = &ImportedTypeInfo{
-				TypeName:            varInfo.TypeName,
-				ImportRaw:           imported.Path.Value,
-				PackagePath:         packagePath,
-				PackageDefaultAlias: parts[len(parts)-1],
+	case *ast.BinaryExpr:
+		if expr.Op != token.OR {
+			break
+		}
+		terms := []*TypeInfo{}
+		for _, term := range []ast.Expr{expr.X, expr.Y} {
+			termInfo := exprToTypeInfo(term, fileCache)
+			if termInfo.IsUnion {
+				terms = append(terms, termInfo.Union...)
+			} else {
+				terms = append(terms, termInfo)
 			}
-			varInfo.TypeName = varInfo.ImportedType.PackageDefaultAlias + "." + varInfo.TypeName
-			varInfo.ExternalTypeName = varInfo.TypeName
 		}
-
-	case *ast.Ident:
-		if expr.Obj != nil && expr.Obj.Decl != nil {
-			switch s := expr.Obj.Decl.(type) {
-			case *ast.TypeSpec:
-				varInfo = exprToTypeInfo(s.Type, fileCache)
-				varInfo.TypeName = s.Name.Name
-				varInfo.ExternalTypeName = fileCache.packageName + "." + varInfo.TypeName
-
-			case *ast.Field:
-				varInfo = exprToTypeInfo(s.Type, fileCache)
-				if varInfo.IsStruct {
-					varInfo.ExternalTypeName = fileCache.packageName + "." + varInfo.TypeName
-				}
+		varInfo = unionTypeInfo(terms)
+	case *ast.StructType:
+		t, ok := fileCache.typesInfo.TypeOf(e).(*types.Struct)
+		if !ok {
+			break
+		}
+		varInfo = typeToTypeInfo(t, fileCache)
+		for _, f := range expr.Fields.List {
+			for _, name := range f.Names {
+				varInfo.Struct.Fields[name.Name].Markers = markerValues(f.Doc, fileCache.markerPrefix)
+				varInfo.Struct.Fields[name.Name].MarkerList = markerList(f.Doc, fileCache)
+				varInfo.Struct.Fields[name.Name].Doc = docText(f.Doc, fileCache.markerPrefix)
+				varInfo.Struct.Fields[name.Name].Position = fileCache.position(f.Pos(), f.End())
 			}
 		}
-		if expr.Obj == nil {
-			varInfo.TypeName = expr.Name
-			varInfo.ExternalTypeName = varInfo.TypeName
-		} else {
-			if expr.Obj.Decl != nil {
-				t, ok := expr.Obj.Decl.(*ast.TypeSpec)
-				if ok {
-					varInfo.TypeOf = exprToTypeInfo(t.Type, fileCache)
-					varInfo.IsType = true
-				}
+	case *ast.InterfaceType:
+		t, ok := fileCache.typesInfo.TypeOf(e).(*types.Interface)
+		if !ok {
+			break
+		}
+		varInfo = typeToTypeInfo(t, fileCache)
+		for _, m := range expr.Methods.List {
+			for _, name := range m.Names {
+				varInfo.Interface.Methods[name.Name].Markers = markerValues(m.Doc, fileCache.markerPrefix)
+				varInfo.Interface.Methods[name.Name].MarkerList = markerList(m.Doc, fileCache)
+				varInfo.Interface.Methods[name.Name].Doc = docText(m.Doc, fileCache.markerPrefix)
+				varInfo.Interface.Methods[name.Name].Position = fileCache.position(m.Pos(), m.End())
 			}
 		}
+	default:
+		t := fileCache.typesInfo.TypeOf(e)
+		if t != nil {
+			varInfo = typeToTypeInfo(t, fileCache)
+		}
 	}
 
 	return varInfo
 }
 
-func handleValueSpec(node *ast.ValueSpec, pi *PackageInfo, fileCache fileCachedData) {
-	if len(node.Names) != 1 {
+func fieldListToTypeParamInfoList(typeParams *ast.FieldList, fileCache fileCachedData) []*TypeParamInfo {
+	if typeParams == nil || len(typeParams.List) == 0 {
+		return nil
+	}
+
+	tps := []*TypeParamInfo{}
+	for _, typeParam := range typeParams.List {
+		for _, name := range typeParam.Names {
+			tps = append(tps, &TypeParamInfo{
+				Name:       name.Name,
+				Constraint: exprToTypeInfo(typeParam.Type, fileCache),
+			})
+		}
+	}
+
+	return tps
+}
+
+// embedsInterface tells an embedded interface from a type set term such as int, ~int or
+// a | b, the same way interfaceTypeInfo does. Without type information it goes by the syntax.
+func embedsInterface(e ast.Expr, fileCache fileCachedData) bool {
+	if t := fileCache.typesInfo.TypeOf(e); t != nil && t != types.Typ[types.Invalid] {
+		_, ok := t.Underlying().(*types.Interface)
+		return ok
+	}
+
+	switch e.(type) {
+	case *ast.BinaryExpr, *ast.UnaryExpr:
+		return false
+	}
+
+	return true
+}
+
+// typeExprName returns the bare name of the type an expression denotes, without package,
+// pointer, parentheses or type arguments: "*pkg.Base[T]" is "Base", the name an embedded field or type gets
+func typeExprName(e ast.Expr) string {
+	switch expr := e.(type) {
+	case *ast.Ident:
+		return expr.Name
+	case *ast.StarExpr:
+		return typeExprName(expr.X)
+	case *ast.ParenExpr:
+		return typeExprName(expr.X)
+	case *ast.SelectorExpr:
+		return expr.Sel.Name
+	case *ast.IndexExpr:
+		return typeExprName(expr.X)
+	case *ast.IndexListExpr:
+		return typeExprName(expr.X)
+	}
+
+	return types.ExprString(e)
+}
+
+func handleGenDecl(node *ast.GenDecl, pi *PackageInfo, fileCache fileCachedData) {
+	if node.Tok == token.TYPE && !node.Lparen.IsValid() && len(node.Specs) == 1 {
+		ts := node.Specs[0].(*ast.TypeSpec)
+		if ts.Doc == nil {
+			ts.Doc = node.Doc
+		}
+	}
+
+	if node.Tok != token.CONST && node.Tok != token.VAR {
 		return
 	}
-	name := node.Names[0]
-
-	for _, val := range node.Values {
-		switch v := val.(type) {
-		case *ast.BasicLit:
-			vs := name.Obj.Decl.(*ast.ValueSpec)
-			if name.Obj.Kind == ast.Con {
-				typeName := strings.ToLower(v.Kind.String())
-				vsType, ok := vs.Type.(*ast.Ident)
-				if ok && vsType != nil && vsType.Name != "" {
-					typeName = vsType.Name
-				}
-				pi.Constants[name.Obj.Name] = &ConstantInfo{
-					Name:     name.Obj.Name,
-					TypeName: typeName,
-					Markers:  markerValues(vs.Doc),
-					Value:    v.Value,
-				}
-			} else {
-				pi.Vars[name.Obj.Name] = &VarInfo{
-					Name:     name.Obj.Name,
-					Markers:  markerValues(vs.Doc),
-					TypeInfo: exprToTypeInfo(v, fileCache),
-				}
+
+	// Constant specs without values repeat the previous expression list
+	var values []ast.Expr
+	for _, spec := range node.Specs {
+		vs := spec.(*ast.ValueSpec)
+		if vs.Doc == nil && !node.Lparen.IsValid() {
+			vs.Doc = node.Doc
+		}
+		if node.Tok == token.VAR || len(vs.Values) > 0 {
+			values = vs.Values
+		}
+
+		handleValueSpec(vs, values, pi, fileCache)
+	}
+}
+
+func handleValueSpec(node *ast.ValueSpec, values []ast.Expr, pi *PackageInfo, fileCache fileCachedData) {
+	for i, name := range node.Names {
+		if name.Name == "_" {
+			continue
+		}
+
+		obj := fileCache.typesInfo.Defs[name]
+		if obj == nil || obj.Parent() != fileCache.types.Scope() {
+			continue
+		}
+
+		value := ""
+		if i < len(values) {
+			value = types.ExprString(values[i])
+		}
+
+		switch o := obj.(type) {
+		case *types.Const:
+			t := o.Type()
+			basic, isBasic := t.(*types.Basic)
+			isUntyped := isBasic && basic.Info()&types.IsUntyped != 0
+			if isUntyped {
+				t = types.Default(t)
 			}
 
-		case *ast.FuncLit:
-			doc, foundDoc := fileCache.commentGroups[node.Pos()-5] // Magic number to get comment group before type name
-			if foundDoc && node.Doc == nil {
-				node.Doc = doc
+			ci := &ConstantInfo{
+				Name:       name.Name,
+				Position:   fileCache.position(name.Pos(), node.End()),
+				TypeName:   typeToTypeInfo(t, fileCache).TypeName,
+				Markers:    markerValues(node.Doc, fileCache.markerPrefix),
+				MarkerList: markerList(node.Doc, fileCache),
+				Doc:        docText(node.Doc, fileCache.markerPrefix),
+				Value:      value,
+				IsUntyped:  isUntyped,
 			}
-			params := fieldListToParamInfoList(v.Type.Params, fileCache)
-			results := fieldListToResultInfoList(v.Type.Results, fileCache)
-
-			pi.Vars[name.Obj.Name] = &VarInfo{
-				Name:    name.Obj.Name,
-				Markers: markerValues(node.Doc),
-				TypeInfo: &TypeInfo{
-					TypeName:         funcTypeNameFromParamsAndResults(params, results, false),
-					ExternalTypeName: funcTypeNameFromParamsAndResults(params, results, true),
-					IsFunc:           true,
-					Func: &FuncDefInfo{
-						IsVariadic: isVariadicFunc(params),
-						Params:     params,
-						Results:    results,
-					},
-				},
+			setConstantValue(ci, o.Val())
+
+			pi.Constants[name.Name] = ci
+		case *types.Var:
+			pi.Vars[name.Name] = &VarInfo{
+				Name:       name.Name,
+				Position:   fileCache.position(name.Pos(), node.End()),
+				Markers:    markerValues(node.Doc, fileCache.markerPrefix),
+				MarkerList: markerList(node.Doc, fileCache),
+				Doc:        docText(node.Doc, fileCache.markerPrefix),
+				TypeInfo:   typeToTypeInfo(o.Type(), fileCache),
 			}
 		}
 	}
@@ -343,20 +545,14 @@ func handleFuncDecl(node *ast.FuncDecl, pi *PackageInfo, fileCache fileCachedDat
 	params := fieldListToParamInfoList(node.Type.Params, fileCache)
 	results := fieldListToResultInfoList(node.Type.Results, fileCache)
 
-	receiverTypeName := ""
-	if node.Recv != nil && len(node.Recv.List) > 0 {
-		receiverType, ok := node.Recv.List[0].Type.(*ast.Ident)
-		if ok {
-			receiverTypeName = receiverType.Name
-		}
-	}
+	fi := &FuncInfo{
+		Name:       node.Name.Name,
+		Position:   fileCache.position(node.Pos(), node.End()),
+		TypeParams: fieldListToTypeParamInfoList(node.Type.TypeParams, fileCache),
 
-	pi.Functions[node.Name.Name] = &FuncInfo{
-		Name:        node.Name.Name,
-		HasReciver:  receiverTypeName != "",
-		ReciverName: receiverTypeName,
-
-		Markers: markerValues(node.Doc),
+		Markers:    markerValues(node.Doc, fileCache.markerPrefix),
+		MarkerList: markerList(node.Doc, fileCache),
+		Doc:        docText(node.Doc, fileCache.markerPrefix),
 		FuncDefInfo: &FuncDefInfo{
 			IsVariadic: isVariadicFunc(params),
 			Params:     params,
@@ -364,103 +560,300 @@ func handleFuncDecl(node *ast.FuncDecl, pi *PackageInfo, fileCache fileCachedDat
 		},
 	}
 
-	for i := range pi.Structs {
-		if receiverTypeName == pi.Structs[i].Name {
-			pi.Structs[i].Methods[node.Name.Name] = pi.Functions[node.Name.Name]
+	if node.Recv != nil && len(node.Recv.List) > 0 {
+		recv := node.Recv.List[0]
+		recvType := recv.Type
+		if paren, ok := recvType.(*ast.ParenExpr); ok {
+			recvType = paren.X
+		}
+		if star, ok := recvType.(*ast.StarExpr); ok {
+			fi.IsPointerReceiver = true
+			recvType = star.X
 		}
+		if len(recv.Names) > 0 {
+			fi.ReceiverVarName = recv.Names[0].Name
+		}
+
+		fi.HasReciver = true
+		fi.ReciverName = typeExprName(recvType)
+		fi.ReceiverType = exprToTypeInfo(recvType, fileCache)
+		fi.IsReceiver = true
+		fi.Receiver = types.ExprString(recv.Type)
+
+		// Methods are attached to their receiver once every file of the package is read
+		fileCache.methods[fi.ReciverName] = append(fileCache.methods[fi.ReciverName], fi)
+		return
 	}
+
+	pi.Functions[node.Name.Name] = fi
 }
 
 type fileCachedData struct {
 	commentGroups map[token.Pos]*ast.CommentGroup
 	imports       map[string]*ast.ImportSpec
-	packageName   string
+	fset          *token.FileSet
+	typesInfo     *types.Info
+	types         *types.Package
+	expanding     map[*types.TypeName]bool
+	markerPrefix  string
+	methods       map[string][]*FuncInfo
+	references    *typeReferences
+	shallow       bool
 }
 
 type Options struct {
 	Path                       string
 	SkipFilesWithContentsRegex []*regexp.Regexp
 	IncludeEmptyPackages       bool
+	// MarkerPrefix marks the comment lines that are markers, e.g. "+" for "// +enum".
+	// Other lines form the Doc text. When empty every comment line is a marker.
+	MarkerPrefix string
+	// Markers reports the markers missing from the registry or misused in Results.Diagnostics
+	Markers *MarkerRegistry
+	// IncludePromoted fills the fields and methods structs get through embedded fields
+	IncludePromoted bool
+	// Patterns are the go list patterns to load relative to Path, "./..." when empty
+	Patterns []string
+	// BuildTags, GOOS, GOARCH and Env select the files of the build, Env entries
+	// are "KEY=value" added to the environment of go list
+	BuildTags []string
+	GOOS      string
+	GOARCH    string
+	Env       []string
+	// Tests parses packages with their _test.go files and the external p_test packages
+	Tests bool
+	// Overlay replaces or adds file contents by path, relative paths are joined to Path
+	Overlay map[string][]byte
+	// ContinueOnError parses the packages with list, syntax or type errors as far as
+	// they loaded instead of leaving them out and returning ErrPackageErrors
+	ContinueOnError bool
+	// Concurrency is the number of packages parsed at once, GOMAXPROCS when zero
+	// and one by one when 1. The results do not depend on it.
+	Concurrency int
+	// CacheDir keeps the parsed packages between runs, packages whose files, dependencies
+	// and options did not change are read back instead of parsed. No cache when empty.
+	CacheDir string
 }
 
 func (p *Parser) ParseDirectory(opts Options) (*Results, error) {
-	results := &Results{
-		Packages: map[string]*PackageInfo{},
+	loaded, err := loadPackages(opts)
+	if err != nil {
+		return &Results{Packages: map[string]*PackageInfo{}}, err
 	}
 
+	return extractPackages(loaded, opts)
+}
+
+// loadedPackages are the packages to parse once loaded, with the diagnostics of the broken ones
+type loadedPackages struct {
+	pkgs        []*packages.Package
+	diagnostics []Diagnostic
+	broken      int
+	env         []string
+	overlay     map[string][]byte
+}
+
+func loadPackages(opts Options) (*loadedPackages, error) {
 	cfg := &packages.Config{
 		Mode: packages.NeedSyntax | packages.NeedTypes | packages.NeedDeps | packages.NeedFiles | packages.NeedName |
 			packages.NeedTypesInfo | packages.NeedModule | packages.NeedEmbedFiles | packages.NeedEmbedPatterns |
-			packages.NeedTarget | packages.NeedCompiledGoFiles | packages.NeedExportFile | packages.NeedImports |
+			packages.NeedTarget | packages.NeedCompiledGoFiles | packages.NeedImports |
 			packages.NeedForTest,
-		Dir: opts.Path,
+		Dir:     opts.Path,
+		Tests:   opts.Tests,
+		Overlay: overlay(opts),
+	}
+	cfg.Env, cfg.BuildFlags = buildConfig(opts)
+
+	patterns := opts.Patterns
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
 	}
 
-	pkgs, err := packages.Load(cfg, "./...")
+	pkgs, err := packages.Load(cfg, patterns...)
 	if err != nil {
-		log.Fatal(err)
+		return nil, fmt.Errorf("load packages: %w", err)
+	}
+	if opts.Tests {
+		pkgs = testVariants(pkgs)
 	}
 
+	loaded := &loadedPackages{env: cfg.Env, overlay: cfg.Overlay}
 	for _, pkg := range pkgs {
-		pi := &PackageInfo{
-			Name:         pkg.Name,
-			Structs:      map[string]*StructInfo{},
-			Functions:    map[string]*FuncInfo{},
-			Interfaces:   map[string]*InterfaceInfo{},
-			Vars:         map[string]*VarInfo{},
-			Constants:    map[string]*ConstantInfo{},
-			DefinedTypes: map[string]*DefinedTypeInfo{},
-			Aliases:      map[string]*AliasTypeInfo{},
-		}
-		for _, file := range pkg.Syntax {
-			fileCacheData := fileCachedData{
-				commentGroups: map[token.Pos]*ast.CommentGroup{},
-				imports:       map[string]*ast.ImportSpec{},
-				packageName:   pkg.Name,
+		diagnostics := packageDiagnostics(pkg)
+		loaded.diagnostics = append(loaded.diagnostics, diagnostics...)
+		if len(diagnostics) > 0 {
+			loaded.broken++
+			if !opts.ContinueOnError || pkg.Types == nil || pkg.TypesInfo == nil {
+				continue
 			}
+		}
+		loaded.pkgs = append(loaded.pkgs, pkg)
+	}
+
+	return loaded, nil
+}
+
+// extractPackages builds the results of the loaded packages, it does not change them
+func extractPackages(loaded *loadedPackages, opts Options) (*Results, error) {
+	results := &Results{
+		Packages:    map[string]*PackageInfo{},
+		Diagnostics: append([]Diagnostic(nil), loaded.diagnostics...),
+	}
+	pkgs := loaded.pkgs
+
+	roots := map[string]bool{}
+	for _, pkg := range pkgs {
+		roots[pkg.PkgPath] = true
+	}
 
-			skipFile := false
-			for i := range file.Comments {
-				for j := range opts.SkipFilesWithContentsRegex {
-					if opts.SkipFilesWithContentsRegex[j].MatchString(file.Comments[i].Text()) {
-						skipFile = true
-						break
-					}
+	cache := newPackageCache(opts, loaded.env, loaded.overlay)
+	cache.hashPackages(pkgs)
+
+	// Packages are parsed concurrently with their own state and merged in load order
+	parsed := make([]parsedPackage, len(pkgs))
+	parallel(len(pkgs), opts.Concurrency, func(i int) {
+		parsed[i] = cache.parse(pkgs[i], opts, loaded.env)
+	})
+
+	// Named types met in any package, linked to their declarations once every package is read
+	references := newTypeReferences()
+	for _, pp := range parsed {
+		results.Packages[pp.info.Path] = pp.info
+		results.Diagnostics = append(results.Diagnostics, pp.diagnostics...)
+		references.merge(pp.references)
+	}
+
+	table := newTypeTable(roots)
+	table.build(references)
+	table.markRecursive(references.infos)
+	results.Types = table.types
+	linkReferences(results, references.infos)
+	collectImplementations(results, pkgs)
+	sortDiagnostics(results.Diagnostics)
+
+	if loaded.broken > 0 && !opts.ContinueOnError {
+		return results, fmt.Errorf("%w: %d packages, see Results.Diagnostics", ErrPackageErrors, loaded.broken)
+	}
+
+	return results, nil
+}
+
+type parsedPackage struct {
+	info        *PackageInfo
+	diagnostics []Diagnostic
+	references  *typeReferences
+}
+
+// parsePackage extracts the package, it only reads the loaded package and the options
+// so packages can be parsed concurrently
+func parsePackage(pkg *packages.Package, opts Options, env []string) parsedPackage {
+	pi := &PackageInfo{
+		Name:         pkg.Name,
+		Path:         pkg.PkgPath,
+		Dir:          pkg.Dir,
+		GoFiles:      pkg.GoFiles,
+		Module:       moduleInfo(pkg.Module),
+		Build:        buildInfo(opts, env, pkg),
+		Structs:      map[string]*StructInfo{},
+		Functions:    map[string]*FuncInfo{},
+		Interfaces:   map[string]*InterfaceInfo{},
+		Vars:         map[string]*VarInfo{},
+		Constants:    map[string]*ConstantInfo{},
+		DefinedTypes: map[string]*DefinedTypeInfo{},
+		Aliases:      map[string]*AliasTypeInfo{},
+		Enums:        map[string]*EnumInfo{},
+	}
+	methods := map[string][]*FuncInfo{}
+	references := newTypeReferences()
+	for _, file := range pkg.Syntax {
+		fileCacheData := fileCachedData{
+			commentGroups: map[token.Pos]*ast.CommentGroup{},
+			imports:       map[string]*ast.ImportSpec{},
+			fset:          pkg.Fset,
+			typesInfo:     pkg.TypesInfo,
+			types:         pkg.Types,
+			expanding:     map[*types.TypeName]bool{},
+			markerPrefix:  opts.MarkerPrefix,
+			methods:       methods,
+			references:    references,
+		}
+
+		skipFile := false
+		for i := range file.Comments {
+			for j := range opts.SkipFilesWithContentsRegex {
+				if opts.SkipFilesWithContentsRegex[j].MatchString(file.Comments[i].Text()) {
+					skipFile = true
+					break
 				}
 			}
-			if skipFile {
-				continue
+		}
+		if skipFile {
+			continue
+		}
+
+		pi.Doc += docText(file.Doc, opts.MarkerPrefix)
+
+		ast.Inspect(file, func(n ast.Node) bool {
+			switch node := n.(type) {
+			case *ast.GenDecl:
+				handleGenDecl(node, pi, fileCacheData)
+			case *ast.FuncDecl:
+				handleFuncDecl(node, pi, fileCacheData)
+			case *ast.CommentGroup:
+				fileCacheData.commentGroups[node.End()] = node
+			case *ast.ImportSpec:
+				fileCacheData.imports[strings.ReplaceAll(node.Path.Value, "\"", "")] = node
+
+			case *ast.TypeSpec:
+				handleTypeSpec(node, pi, fileCacheData)
+
+			default:
 			}
 
-			ast.Inspect(file, func(n ast.Node) bool {
-				switch node := n.(type) {
-				case *ast.ValueSpec:
-					handleValueSpec(node, pi, fileCacheData)
-				case *ast.FuncDecl:
-					handleFuncDecl(node, pi, fileCacheData)
-				case *ast.CommentGroup:
-					fileCacheData.commentGroups[node.End()] = node
-				case *ast.ImportSpec:
-					if node.Name == nil {
-						fileCacheData.imports[strings.ReplaceAll(node.Path.Value, "\"", "")] = node
-					} else {
-						fileCacheData.imports[node.Name.Name] = node
-					}
-
-				case *ast.TypeSpec:
-					handleTypeSpec(node, pi, fileCacheData)
-
-				default:
-				}
+			return true
+		})
+	}
 
-				return true
-			})
-		}
+	pkgCache := fileCachedData{
+		commentGroups: map[token.Pos]*ast.CommentGroup{},
+		imports:       map[string]*ast.ImportSpec{},
+		fset:          pkg.Fset,
+		typesInfo:     pkg.TypesInfo,
+		types:         pkg.Types,
+		expanding:     map[*types.TypeName]bool{},
+		markerPrefix:  opts.MarkerPrefix,
+		methods:       methods,
+		references:    references,
+	}
+	declareTypes(pi, pkgCache)
+	attachMethods(pi, pkgCache)
+	if opts.IncludePromoted {
+		promoteMembers(pi, pkgCache)
+	}
+
+	orderPackageInfo(pi)
+	collectEnums(pi)
 
-		results.Packages[pi.Name] = pi
+	diagnostics := []Diagnostic{}
+	if opts.Markers != nil {
+		diagnostics = opts.Markers.validatePackage(pi)
 	}
 
-	return results, err
+	return parsedPackage{info: pi, diagnostics: diagnostics, references: references}
+}
+
+func moduleInfo(module *packages.Module) *ModuleInfo {
+	if module == nil {
+		return nil
+	}
+
+	return &ModuleInfo{
+		Path:      module.Path,
+		Version:   module.Version,
+		GoVersion: module.GoVersion,
+		Main:      module.Main,
+	}
 }
 
 func handleInterfaceType(ts *ast.TypeSpec, node *ast.InterfaceType, pi *PackageInfo, fileCache fileCachedData) {
@@ -471,27 +864,42 @@ func handleInterfaceType(ts *ast.TypeSpec, node *ast.InterfaceType, pi *PackageI
 
 	ii := &InterfaceInfo{
 		Name:          ts.Name.Name,
-		Markers:       markerValues(ts.Doc),
+		Position:      fileCache.position(ts.Pos(), ts.End()),
+		Markers:       markerValues(ts.Doc, fileCache.markerPrefix),
+		MarkerList:    markerList(ts.Doc, fileCache),
+		Doc:           docText(ts.Doc, fileCache.markerPrefix),
+		TypeParams:    fieldListToTypeParamInfoList(ts.TypeParams, fileCache),
 		Methods:       map[string]*FuncInfo{},
 		EmbeddedTypes: map[string]*EmbeddedTypeInfo{},
 	}
 	for i, m := range node.Methods.List {
 		if len(node.Methods.List[i].Names) == 0 {
+			if !embedsInterface(m.Type, fileCache) {
+				ii.Constraints = append(ii.Constraints, exprToTypeInfo(m.Type, fileCache))
+				continue
+			}
+
 			eti := &EmbeddedTypeInfo{
-				Name:     m.Type.(*ast.Ident).Name,
-				TypeName: m.Type.(*ast.Ident).Name,
-				Markers:  markerValues(m.Doc),
+				Name:       typeExprName(m.Type),
+				Position:   fileCache.position(m.Pos(), m.End()),
+				Markers:    markerValues(m.Doc, fileCache.markerPrefix),
+				MarkerList: markerList(m.Doc, fileCache),
+				Doc:        docText(m.Doc, fileCache.markerPrefix),
+				TypeInfo:   exprToTypeInfo(m.Type, fileCache),
 			}
 
-			ii.EmbeddedTypes[eti.TypeName] = eti
+			ii.EmbeddedTypes[eti.Name] = eti
 		} else {
 			params := fieldListToParamInfoList(m.Type.(*ast.FuncType).Params, fileCache)
 			results := fieldListToResultInfoList(m.Type.(*ast.FuncType).Results, fileCache)
 
 			funcName := node.Methods.List[i].Names[0].Name
 			ii.Methods[funcName] = &FuncInfo{
-				Name:    funcName,
-				Markers: markerValues(node.Methods.List[i].Doc),
+				Name:       funcName,
+				Position:   fileCache.position(m.Pos(), m.End()),
+				Markers:    markerValues(node.Methods.List[i].Doc, fileCache.markerPrefix),
+				MarkerList: markerList(node.Methods.List[i].Doc, fileCache),
+				Doc:        docText(node.Methods.List[i].Doc, fileCache.markerPrefix),
 
 				FuncDefInfo: &FuncDefInfo{
 					IsVariadic: isVariadicFunc(params),
@@ -525,7 +933,7 @@ func fieldListToParamInfoList(params *ast.FieldList, fileCache fileCachedData) [
 		for _, paramNameProperties := range param.Names {
 			ps = append(ps, &ParamInfo{
 				Name:       paramNameProperties.Name,
-				TypeInfo:   exprToTypeInfo(paramNameProperties, fileCache),
+				TypeInfo:   exprToTypeInfo(param.Type, fileCache),
 				IsVariadic: false,
 			})
 		}
@@ -550,7 +958,7 @@ func fieldListToResultInfoList(results *ast.FieldList, fileCache fileCachedData)
 		for _, paramNameProperties := range result.Names {
 			rs = append(rs, &ResultInfo{
 				Name:     paramNameProperties.Name,
-				TypeInfo: exprToTypeInfo(paramNameProperties, fileCache),
+				TypeInfo: exprToTypeInfo(result.Type, fileCache),
 			})
 		}
 		if result.Type != nil && len(result.Names) == 0 {
@@ -564,6 +972,11 @@ func fieldListToResultInfoList(results *ast.FieldList, fileCache fileCachedData)
 }
 
 func handleTypeSpec(ts *ast.TypeSpec, pi *PackageInfo, fileCache fileCachedData) {
+	obj := fileCache.typesInfo.Defs[ts.Name]
+	if obj == nil || obj.Parent() != fileCache.types.Scope() {
+		return
+	}
+
 	switch t := ts.Type.(type) {
 	case *ast.StructType:
 		handleStructType(ts, t, pi, fileCache)
@@ -576,17 +989,26 @@ func handleTypeSpec(ts *ast.TypeSpec, pi *PackageInfo, fileCache fileCachedData)
 
 		if ts.Assign == token.NoPos {
 			dti := &DefinedTypeInfo{
-				Name:     ts.Name.Name,
-				Markers:  markerValues(ts.Doc),
-				TypeInfo: exprToTypeInfo(t, fileCache),
+				Name:       ts.Name.Name,
+				Methods:    map[string]*FuncInfo{},
+				Position:   fileCache.position(ts.Pos(), ts.End()),
+				Markers:    markerValues(ts.Doc, fileCache.markerPrefix),
+				MarkerList: markerList(ts.Doc, fileCache),
+				Doc:        docText(ts.Doc, fileCache.markerPrefix),
+				TypeParams: fieldListToTypeParamInfoList(ts.TypeParams, fileCache),
+				TypeInfo:   exprToTypeInfo(t, fileCache),
 			}
 
 			pi.DefinedTypes[dti.Name] = dti
 		} else {
 			ati := &AliasTypeInfo{
-				Name:     ts.Name.Name,
-				Markers:  markerValues(ts.Doc),
-				TypeInfo: exprToTypeInfo(t, fileCache),
+				Name:       ts.Name.Name,
+				Position:   fileCache.position(ts.Pos(), ts.End()),
+				Markers:    markerValues(ts.Doc, fileCache.markerPrefix),
+				MarkerList: markerList(ts.Doc, fileCache),
+				Doc:        docText(ts.Doc, fileCache.markerPrefix),
+				TypeParams: fieldListToTypeParamInfoList(ts.TypeParams, fileCache),
+				TypeInfo:   exprToTypeInfo(t, fileCache),
 			}
 
 			pi.Aliases[ati.Name] = ati
@@ -597,7 +1019,9 @@ func handleTypeSpec(ts *ast.TypeSpec, pi *PackageInfo, fileCache fileCachedData)
 
 func handleStructType(t *ast.TypeSpec, s *ast.StructType, pi *PackageInfo, fileCache fileCachedData) {
 	si := &StructInfo{
-		Name: t.Name.Name,
+		Name:       t.Name.Name,
+		Position:   fileCache.position(t.Pos(), t.End()),
+		TypeParams: fieldListToTypeParamInfoList(t.TypeParams, fileCache),
 	}
 
 	doc, foundDoc := fileCache.commentGroups[t.Name.Pos()-6] // Magic number to get comment group before type name
@@ -605,7 +1029,9 @@ func handleStructType(t *ast.TypeSpec, s *ast.StructType, pi *PackageInfo, fileC
 		t.Doc = doc
 	}
 
-	si.Markers = markerValues(t.Doc)
+	si.Markers = markerValues(t.Doc, fileCache.markerPrefix)
+	si.MarkerList = markerList(t.Doc, fileCache)
+	si.Doc = docText(t.Doc, fileCache.markerPrefix)
 	si.Fields = map[string]*FieldInfo{}
 	si.Methods = map[string]*FuncInfo{}
 	si.EmbeddedFields = map[string]EmbeddedFieldInfo{}
@@ -617,76 +1043,36 @@ func handleStructType(t *ast.TypeSpec, s *ast.StructType, pi *PackageInfo, fileC
 
 		if len(f.Names) == 0 {
 			efi := EmbeddedFieldInfo{
-				Name:     f.Type.(*ast.Ident).Name,
-				TypeName: f.Type.(*ast.Ident).Name,
-				Markers:  markerValues(f.Doc),
-				Tags:     parseTags(f.Tag),
+				Name:       typeExprName(f.Type),
+				Position:   fileCache.position(f.Pos(), f.End()),
+				Markers:    markerValues(f.Doc, fileCache.markerPrefix),
+				MarkerList: markerList(f.Doc, fileCache),
+				Doc:        docText(f.Doc, fileCache.markerPrefix),
+				RawTag:     rawTag(f.Tag),
+				Tags:       parseRawTags(rawTag(f.Tag)),
+				TagList:    parseTagList(rawTag(f.Tag)),
+				TypeInfo:   exprToTypeInfo(f.Type, fileCache),
 			}
 
-			si.EmbeddedFields[efi.TypeName] = efi
+			si.EmbeddedFields[efi.Name] = efi
 		} else {
-			fi := &FieldInfo{
-				Name:     f.Names[0].Name,
-				TypeInfo: exprToTypeInfo(f.Type, fileCache),
-				Tags:     parseTags(f.Tag),
-				Markers:  markerValues(f.Doc),
-			}
-
-			si.Fields[fi.Name] = fi
-		}
-	}
-
-	for i := range pi.Functions {
-		if pi.Functions[i].HasReciver && pi.Functions[i].ReciverName == si.Name {
+			for _, name := range f.Names {
+				fi := &FieldInfo{
+					Name:       name.Name,
+					Position:   fileCache.position(name.Pos(), f.End()),
+					TypeInfo:   exprToTypeInfo(f.Type, fileCache),
+					RawTag:     rawTag(f.Tag),
+					Tags:       parseRawTags(rawTag(f.Tag)),
+					TagList:    parseTagList(rawTag(f.Tag)),
+					Markers:    markerValues(f.Doc, fileCache.markerPrefix),
+					MarkerList: markerList(f.Doc, fileCache),
+					Doc:        docText(f.Doc, fileCache.markerPrefix),
+				}
 
-			si.Methods[pi.Functions[i].Name] = pi.Functions[i]
+				si.Fields[fi.Name] = fi
+			}
 		}
 	}
 
 	pi.Structs[si.Name] = si
 }
-
-func parseTags(tagLit *ast.BasicLit) map[string][]string {
-	if tagLit == nil || tagLit.Kind != token.STRING {
-		return map[string][]string{}
-	}
-
-	tags := map[string][]string{}
-
-	raw := strings.ReplaceAll(tagLit.Value, "`", "")
-	tagGroups := strings.Split(raw, " ")
-	for _, group := range tagGroups {
-		parts := strings.SplitN(group, ":", 2)
-		if len(parts) != 2 {
-			continue
-		}
-		tags[parts[0]] = strings.Split(strings.ReplaceAll(parts[1], "\"", ""), ",")
-	}
-
-	_ = reflect.StructTag(raw)
-
-	return tags
-}
-
-func markerValues(cg *ast.CommentGroup) map[string]string {
-	if cg == nil {
-		return map[string]string{}
-	}
-
-	values := map[string]string{}
-
-	for _, c := range cg.List {
-		txt := strings.TrimSpace(strings.TrimPrefix(c.Text, "//"))
-		txt = strings.TrimSpace(strings.TrimPrefix(txt, "/*"))
-		txt = strings.TrimSpace(strings.TrimSuffix(txt, "*/"))
-		parts := strings.SplitN(txt, "=", 2)
-		if len(parts) == 2 {
-			values[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
-		} else {
-			values[strings.TrimSpace(parts[0])] = ""
-		}
-
-	}
-
-	return values
-}
@@ -0,0 +1,97 @@
+package parse
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeBenchmarkModule writes a module of packages each importing the previous one
+func writeBenchmarkModule(b *testing.B, packages, structs, fields int) string {
+	b.Helper()
+
+	dir := b.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module bench\n\ngo 1.24\n"), 0o644); err != nil {
+		b.Fatal(err)
+	}
+
+	for p := 0; p < packages; p++ {
+		var src strings.Builder
+		fmt.Fprintf(&src, "package pkg%d\n\n", p)
+		if p > 0 {
+			fmt.Fprintf(&src, "import \"bench/pkg%d\"\n\n", p-1)
+		}
+
+		for s := 0; s < structs; s++ {
+			fmt.Fprintf(&src, "// +gen:builder\ntype Struct%d struct {\n", s)
+			for f := 0; f < fields; f++ {
+				fmt.Fprintf(&src, "\tField%d map[string][]*Struct%d `json:\"field%d,omitempty\"`\n", f, (s+f)%structs, f)
+			}
+			if p > 0 {
+				fmt.Fprintf(&src, "\tPrevious pkg%d.Struct%d\n", p-1, s)
+			}
+			fmt.Fprintf(&src, "}\n\nfunc (s *Struct%d) Method(in Struct%d) (Struct%d, error) {\n\treturn in, nil\n}\n\n", s, s, s)
+		}
+
+		pkgDir := filepath.Join(dir, fmt.Sprintf("pkg%d", p))
+		if err := os.MkdirAll(pkgDir, 0o755); err != nil {
+			b.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(pkgDir, "structs.go"), []byte(src.String()), 0o644); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+// benchmarkConcurrency are the settings both benchmarks run with, one by one and GOMAXPROCS
+var benchmarkConcurrency = []struct {
+	name        string
+	concurrency int
+}{
+	{"concurrency=1", 1},
+	{"concurrency=GOMAXPROCS", 0},
+}
+
+// BenchmarkParser_ParseDirectory times a whole call, loading and type checking the packages included
+func BenchmarkParser_ParseDirectory(b *testing.B) {
+	dir := writeBenchmarkModule(b, 40, 30, 10)
+
+	for _, bc := range benchmarkConcurrency {
+		b.Run(bc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			p := &Parser{}
+			for i := 0; i < b.N; i++ {
+				if _, err := p.ParseDirectory(Options{Path: dir, MarkerPrefix: "+", IncludePromoted: true, Concurrency: bc.concurrency}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkParser_Extract times the extraction alone, on packages loaded once
+func BenchmarkParser_Extract(b *testing.B) {
+	dir := writeBenchmarkModule(b, 40, 30, 10)
+
+	for _, bc := range benchmarkConcurrency {
+		b.Run(bc.name, func(b *testing.B) {
+			opts := Options{Path: dir, MarkerPrefix: "+", IncludePromoted: true, Concurrency: bc.concurrency}
+			loaded, err := loadPackages(opts)
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := extractPackages(loaded, opts); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
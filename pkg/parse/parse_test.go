@@ -1,15 +1,35 @@
 package parse_test
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"github.com/gocloud9/gen-tool/pkg/parse"
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"maps"
 	"os"
 	"path/filepath"
+	"reflect"
 	"regexp"
+	"slices"
 
 	"testing"
 )
 
+// ignoreLocationAndOrder skips the machine dependent locations, the ordered
+// lists and the structured markers, which are covered by their own tests
+var ignoreLocationAndOrder = cmp.Options{
+	cmpopts.IgnoreTypes(parse.Position{}, []*parse.Marker{}, parse.TagList{}),
+	cmpopts.IgnoreFields(parse.PackageInfo{}, "Dir", "GoFiles", "Build",
+		"StructList", "ConstantList", "FunctionList", "InterfaceList", "VarList", "DefinedTypeList", "AliasList", "EnumList"),
+	cmpopts.IgnoreFields(parse.StructInfo{}, "FieldList", "MethodList", "EmbeddedFieldList", "ValueMethodSet", "PointerMethodSet"),
+	cmpopts.IgnoreFields(parse.DefinedTypeInfo{}, "MethodList", "ValueMethodSet", "PointerMethodSet"),
+	cmpopts.IgnoreFields(parse.InterfaceInfo{}, "MethodList", "EmbeddedTypeList"),
+	cmpopts.IgnoreFields(parse.Results{}, "Implementers", "Implements", "Types"),
+	cmpopts.IgnoreFields(parse.TypeInfo{}, "StructDecl", "InterfaceDecl", "DefinedTypeDecl", "AliasDecl", "Named"),
+}
+
 func TestParser_ParseDirectory(t *testing.T) {
 	type args struct {
 		path      string
@@ -28,14 +48,17 @@ func TestParser_ParseDirectory(t *testing.T) {
 			},
 			want: &parse.Results{
 				Packages: map[string]*parse.PackageInfo{
-					"simple": {
+					"github.com/gocloud9/gen-tool/pkg/parse/_testdata/simple": {
 						Name:         "simple",
+						Path:         "github.com/gocloud9/gen-tool/pkg/parse/_testdata/simple",
+						Module:       &parse.ModuleInfo{Path: "github.com/gocloud9/gen-tool", GoVersion: "1.24.10", Main: true},
 						Constants:    map[string]*parse.ConstantInfo{},
 						Functions:    map[string]*parse.FuncInfo{},
 						Interfaces:   map[string]*parse.InterfaceInfo{},
 						Vars:         map[string]*parse.VarInfo{},
 						DefinedTypes: map[string]*parse.DefinedTypeInfo{},
 						Aliases:      map[string]*parse.AliasTypeInfo{},
+						Enums:        map[string]*parse.EnumInfo{},
 						Structs: map[string]*parse.StructInfo{
 							"User": {
 								Name: "User",
@@ -45,7 +68,8 @@ func TestParser_ParseDirectory(t *testing.T) {
 								},
 								Fields: map[string]*parse.FieldInfo{
 									"ID": {
-										Name: "ID",
+										Name:   "ID",
+										RawTag: `json:"id"`,
 										Tags: map[string][]string{
 											"json": {"id"},
 										},
@@ -58,7 +82,8 @@ func TestParser_ParseDirectory(t *testing.T) {
 										},
 									},
 									"DisplayName": {
-										Name: "DisplayName",
+										Name:   "DisplayName",
+										RawTag: `json:"display_name"`,
 										Tags: map[string][]string{
 											"json": {"display_name"},
 										},
@@ -74,7 +99,8 @@ func TestParser_ParseDirectory(t *testing.T) {
 										Markers: map[string]string{},
 									},
 									"Email": {
-										Name: "Email",
+										Name:   "Email",
+										RawTag: `json:"email"`,
 										Tags: map[string][]string{
 											"json": {"email"},
 										},
@@ -85,7 +111,8 @@ func TestParser_ParseDirectory(t *testing.T) {
 										Markers: map[string]string{},
 									},
 									"Age": {
-										Name: "Age",
+										Name:   "Age",
+										RawTag: `json:"age"`,
 										Tags: map[string][]string{
 											"json": {"age"},
 										},
@@ -115,10 +142,13 @@ func TestParser_ParseDirectory(t *testing.T) {
 			},
 			want: &parse.Results{
 				Packages: map[string]*parse.PackageInfo{
-					"package1": {
+					"permutation/package1": {
 						Name:         "package1",
+						Path:         "permutation/package1",
+						Module:       &parse.ModuleInfo{Path: "permutation", GoVersion: "1.25.1", Main: true},
 						DefinedTypes: map[string]*parse.DefinedTypeInfo{},
 						Aliases:      map[string]*parse.AliasTypeInfo{},
+						Enums:        map[string]*parse.EnumInfo{},
 						Structs: map[string]*parse.StructInfo{
 							"AnotherUser": {
 								Name: "AnotherUser",
@@ -128,7 +158,8 @@ func TestParser_ParseDirectory(t *testing.T) {
 								},
 								Fields: map[string]*parse.FieldInfo{
 									"ID": {
-										Name: "ID",
+										Name:   "ID",
+										RawTag: `json:"id"`,
 										Tags: map[string][]string{
 											"json": {"id"},
 										},
@@ -139,7 +170,8 @@ func TestParser_ParseDirectory(t *testing.T) {
 										Markers: map[string]string{"+ID": "true"},
 									},
 									"DisplayName": {
-										Name: "DisplayName",
+										Name:   "DisplayName",
+										RawTag: `json:"display_name"`,
 										Tags: map[string][]string{
 											"json": {"display_name"},
 										},
@@ -152,21 +184,27 @@ func TestParser_ParseDirectory(t *testing.T) {
 									"Duration": {
 										Name:    "Duration",
 										Markers: map[string]string{},
+										RawTag:  `json:"duration"`,
 										Tags:    map[string][]string{"json": {"duration"}},
 										TypeInfo: &parse.TypeInfo{
 											TypeName:         "time.Duration",
 											ExternalTypeName: "time.Duration",
+											PackageName:      "time",
+											PackagePath:      "time",
 											IsImported:       true,
+											IsType:           true,
 											ImportedType: &parse.ImportedTypeInfo{
 												TypeName:            "Duration",
 												ImportRaw:           "\"time\"",
 												PackagePath:         "time",
 												PackageDefaultAlias: "time",
 											},
+											TypeOf: &parse.TypeInfo{TypeName: "int64", ExternalTypeName: "int64"},
 										},
 									},
 									"Email": {
-										Name: "Email",
+										Name:   "Email",
+										RawTag: `json:"email"`,
 										Tags: map[string][]string{
 											"json": {"email"},
 										},
@@ -179,33 +217,46 @@ func TestParser_ParseDirectory(t *testing.T) {
 									"Time": {
 										Name:    "Time",
 										Markers: map[string]string{},
+										RawTag:  `json:"time"`,
 										Tags:    map[string][]string{"json": {"time"}},
 										TypeInfo: &parse.TypeInfo{
 											TypeName:         "time.Time",
 											ExternalTypeName: "time.Time",
+											PackageName:      "time",
+											PackagePath:      "time",
+											IsStruct:         true,
 											IsImported:       true,
+											IsType:           true,
 											ImportedType: &parse.ImportedTypeInfo{
 												TypeName:            "Time",
 												ImportRaw:           "\"time\"",
 												PackagePath:         "time",
 												PackageDefaultAlias: "time",
 											},
+											TypeOf: &parse.TypeInfo{IsStruct: true},
 										},
 									},
 									"Timestamp": {
 										Name:    "Timestamp",
 										Markers: map[string]string{},
+										RawTag:  `json:"timestamp"`,
 										Tags:    map[string][]string{"json": {"timestamp"}},
 										TypeInfo: &parse.TypeInfo{
 											TypeName:         "timestamppb.Timestamp",
 											ExternalTypeName: "timestamppb.Timestamp",
+											PackageName:      "timestamppb",
+											PackagePath:      "google.golang.org/protobuf/types/known/timestamppb",
+											IsStruct:         true,
 											IsImported:       true,
+											IsType:           true,
 											ImportedType: &parse.ImportedTypeInfo{
 												TypeName:            "Timestamp",
 												ImportRaw:           "\"google.golang.org/protobuf/types/known/timestamppb\"",
 												PackagePath:         "google.golang.org/protobuf/types/known/timestamppb",
 												PackageDefaultAlias: "timestamppb",
+												Alias:               "tsproto",
 											},
+											TypeOf: &parse.TypeInfo{IsStruct: true},
 										},
 									},
 								},
@@ -218,10 +269,13 @@ func TestParser_ParseDirectory(t *testing.T) {
 						Interfaces: map[string]*parse.InterfaceInfo{},
 						Vars:       map[string]*parse.VarInfo{},
 					},
-					"package2": {
+					"permutation/package2": {
 						Name:         "package2",
+						Path:         "permutation/package2",
+						Module:       &parse.ModuleInfo{Path: "permutation", GoVersion: "1.25.1", Main: true},
 						DefinedTypes: map[string]*parse.DefinedTypeInfo{},
 						Aliases:      map[string]*parse.AliasTypeInfo{},
+						Enums:        map[string]*parse.EnumInfo{},
 						Structs: map[string]*parse.StructInfo{
 							"SomeStruct": {
 								Name:    "SomeStruct",
@@ -233,6 +287,7 @@ func TestParser_ParseDirectory(t *testing.T) {
 											TypeName:         "string",
 											ExternalTypeName: "string",
 										},
+										RawTag:  `json:"string_field" yaml:"stringField"`,
 										Tags:    map[string][]string{"json": {"string_field"}, "yaml": {"stringField"}},
 										Markers: map[string]string{},
 									},
@@ -242,6 +297,7 @@ func TestParser_ParseDirectory(t *testing.T) {
 											TypeName:         "int",
 											ExternalTypeName: "int",
 										},
+										RawTag:  `json:"int_field" yaml:"intField"`,
 										Tags:    map[string][]string{"json": {"int_field"}, "yaml": {"intField"}},
 										Markers: map[string]string{},
 									},
@@ -251,6 +307,7 @@ func TestParser_ParseDirectory(t *testing.T) {
 											TypeName:         "bool",
 											ExternalTypeName: "bool",
 										},
+										RawTag:  `json:"bool_field" yaml:"boolField"`,
 										Tags:    map[string][]string{"json": {"bool_field"}, "yaml": {"boolField"}},
 										Markers: map[string]string{},
 									},
@@ -260,11 +317,13 @@ func TestParser_ParseDirectory(t *testing.T) {
 											TypeName:         "chan int",
 											ExternalTypeName: "chan int",
 											IsChan:           true,
+											ChanDir:          parse.ChanBoth,
 											Chan: &parse.TypeInfo{
 												TypeName:         "int",
 												ExternalTypeName: "int",
 											},
 										},
+										RawTag:  `json:"chan_field" yaml:"chanField"`,
 										Tags:    map[string][]string{"json": {"chan_field"}, "yaml": {"chanField"}},
 										Markers: map[string]string{},
 									},
@@ -283,6 +342,7 @@ func TestParser_ParseDirectory(t *testing.T) {
 												ExternalTypeName: "int",
 											},
 										},
+										RawTag:  `json:"map_field" yaml:"mapField"`,
 										Tags:    map[string][]string{"json": {"map_field"}, "yaml": {"mapField"}},
 										Markers: map[string]string{},
 									},
@@ -297,6 +357,7 @@ func TestParser_ParseDirectory(t *testing.T) {
 												ExternalTypeName: "int",
 											},
 										},
+										RawTag:  `json:"slice_field" yaml:"sliceField"`,
 										Tags:    map[string][]string{"json": {"slice_field"}, "yaml": {"sliceField"}},
 										Markers: map[string]string{},
 									},
@@ -305,12 +366,15 @@ func TestParser_ParseDirectory(t *testing.T) {
 										TypeInfo: &parse.TypeInfo{
 											TypeName:         "SubStruct",
 											ExternalTypeName: "package2.SubStruct",
+											PackageName:      "package2",
+											PackagePath:      "permutation/package2",
 											IsStruct:         true,
 											IsType:           true,
 											TypeOf: &parse.TypeInfo{
 												IsStruct: true,
 											},
 										},
+										RawTag:  `json:"sub_struct_field" yaml:"subStructField"`,
 										Tags:    map[string][]string{"json": {"sub_struct_field"}, "yaml": {"subStructField"}},
 										Markers: map[string]string{},
 									},
@@ -327,6 +391,8 @@ func TestParser_ParseDirectory(t *testing.T) {
 											MapValue: &parse.TypeInfo{
 												TypeName:         "SubStruct",
 												ExternalTypeName: "package2.SubStruct",
+												PackageName:      "package2",
+												PackagePath:      "permutation/package2",
 												IsStruct:         true,
 												IsType:           true,
 												TypeOf: &parse.TypeInfo{
@@ -334,6 +400,7 @@ func TestParser_ParseDirectory(t *testing.T) {
 												},
 											},
 										},
+										RawTag:  `json:"sub_struct_map_field" yaml:"subStructMapField"`,
 										Tags:    map[string][]string{"json": {"sub_struct_map_field"}, "yaml": {"subStructMapField"}},
 										Markers: map[string]string{},
 									},
@@ -346,6 +413,8 @@ func TestParser_ParseDirectory(t *testing.T) {
 											Slice: &parse.TypeInfo{
 												TypeName:         "SubStruct",
 												ExternalTypeName: "package2.SubStruct",
+												PackageName:      "package2",
+												PackagePath:      "permutation/package2",
 												IsStruct:         true,
 												IsType:           true,
 												TypeOf: &parse.TypeInfo{
@@ -353,6 +422,7 @@ func TestParser_ParseDirectory(t *testing.T) {
 												},
 											},
 										},
+										RawTag:  `json:"sub_struct_slice_field" yaml:"subStructSliceField"`,
 										Tags:    map[string][]string{"json": {"sub_struct_slice_field"}, "yaml": {"subStructSliceField"}},
 										Markers: map[string]string{},
 									},
@@ -392,11 +462,14 @@ func TestParser_ParseDirectory(t *testing.T) {
 			},
 			want: &parse.Results{
 				Packages: map[string]*parse.PackageInfo{
-					"functions": {
+					"github.com/gocloud9/gen-tool/pkg/parse/_testdata/functions": {
 						Name:         "functions",
+						Path:         "github.com/gocloud9/gen-tool/pkg/parse/_testdata/functions",
+						Module:       &parse.ModuleInfo{Path: "github.com/gocloud9/gen-tool", GoVersion: "1.24.10", Main: true},
 						Constants:    map[string]*parse.ConstantInfo{},
 						DefinedTypes: map[string]*parse.DefinedTypeInfo{},
 						Aliases:      map[string]*parse.AliasTypeInfo{},
+						Enums:        map[string]*parse.EnumInfo{},
 						Structs: map[string]*parse.StructInfo{
 							"Field": {
 								Name: "Field",
@@ -414,12 +487,16 @@ func TestParser_ParseDirectory(t *testing.T) {
 											"+Bar": "123",
 										},
 										FuncDefInfo: &parse.FuncDefInfo{
+											IsReceiver: true,
+											Receiver:   "Field",
 											Params: []*parse.ParamInfo{
 												{
 													Name: "arg",
 													TypeInfo: &parse.TypeInfo{
 														TypeName:         "Field",
 														ExternalTypeName: "functions.Field",
+														PackageName:      "functions",
+														PackagePath:      "github.com/gocloud9/gen-tool/pkg/parse/_testdata/functions",
 														IsStruct:         true,
 														IsType:           true,
 														TypeOf:           &parse.TypeInfo{IsStruct: true},
@@ -430,6 +507,15 @@ func TestParser_ParseDirectory(t *testing.T) {
 										},
 										HasReciver:  true,
 										ReciverName: "Field",
+										ReceiverType: &parse.TypeInfo{
+											TypeName:         "Field",
+											ExternalTypeName: "functions.Field",
+											PackageName:      "functions",
+											PackagePath:      "github.com/gocloud9/gen-tool/pkg/parse/_testdata/functions",
+											IsStruct:         true,
+											IsType:           true,
+											TypeOf:           &parse.TypeInfo{IsStruct: true},
+										},
 									},
 								},
 							},
@@ -439,7 +525,46 @@ func TestParser_ParseDirectory(t *testing.T) {
 									"+Foo": "true",
 									"+Bar": "123",
 								},
-								Methods:        map[string]*parse.FuncInfo{},
+								Methods: map[string]*parse.FuncInfo{
+									"Test6": {
+										Name: "Test6",
+										Markers: map[string]string{
+											"+Foo": "true",
+											"+Bar": "123",
+										},
+										FuncDefInfo: &parse.FuncDefInfo{
+											IsReceiver: true,
+											Receiver:   "*Reference",
+											Params: []*parse.ParamInfo{
+												{
+													Name: "arg",
+													TypeInfo: &parse.TypeInfo{
+														TypeName:         "Field",
+														ExternalTypeName: "functions.Field",
+														PackageName:      "functions",
+														PackagePath:      "github.com/gocloud9/gen-tool/pkg/parse/_testdata/functions",
+														IsStruct:         true,
+														IsType:           true,
+														TypeOf:           &parse.TypeInfo{IsStruct: true},
+													},
+												},
+											},
+											Results: []*parse.ResultInfo{},
+										},
+										HasReciver:  true,
+										ReciverName: "Reference",
+										ReceiverType: &parse.TypeInfo{
+											TypeName:         "Reference",
+											ExternalTypeName: "functions.Reference",
+											PackageName:      "functions",
+											PackagePath:      "github.com/gocloud9/gen-tool/pkg/parse/_testdata/functions",
+											IsStruct:         true,
+											IsType:           true,
+											TypeOf:           &parse.TypeInfo{IsStruct: true},
+										},
+										IsPointerReceiver: true,
+									},
+								},
 								Fields:         map[string]*parse.FieldInfo{},
 								EmbeddedFields: map[string]parse.EmbeddedFieldInfo{},
 							},
@@ -537,52 +662,8 @@ func TestParser_ParseDirectory(t *testing.T) {
 											TypeInfo: &parse.TypeInfo{
 												TypeName:         "Field",
 												ExternalTypeName: "functions.Field",
-												IsStruct:         true,
-												IsType:           true,
-												TypeOf:           &parse.TypeInfo{IsStruct: true},
-											},
-										},
-									},
-									Results: []*parse.ResultInfo{},
-								},
-							},
-							"Test5": {
-								Name: "Test5",
-								Markers: map[string]string{
-									"+Foo": "true",
-									"+Bar": "123",
-								},
-								FuncDefInfo: &parse.FuncDefInfo{
-									Params: []*parse.ParamInfo{
-										{
-											Name: "arg",
-											TypeInfo: &parse.TypeInfo{
-												TypeName:         "Field",
-												ExternalTypeName: "functions.Field",
-												IsStruct:         true,
-												IsType:           true,
-												TypeOf:           &parse.TypeInfo{IsStruct: true},
-											},
-										},
-									},
-									Results: []*parse.ResultInfo{},
-								},
-								HasReciver:  true,
-								ReciverName: "Field",
-							},
-							"Test6": {
-								Name: "Test6",
-								Markers: map[string]string{
-									"+Foo": "true",
-									"+Bar": "123",
-								},
-								FuncDefInfo: &parse.FuncDefInfo{
-									Params: []*parse.ParamInfo{
-										{
-											Name: "arg",
-											TypeInfo: &parse.TypeInfo{
-												TypeName:         "Field",
-												ExternalTypeName: "functions.Field",
+												PackageName:      "functions",
+												PackagePath:      "github.com/gocloud9/gen-tool/pkg/parse/_testdata/functions",
 												IsStruct:         true,
 												IsType:           true,
 												TypeOf:           &parse.TypeInfo{IsStruct: true},
@@ -607,6 +688,8 @@ func TestParser_ParseDirectory(t *testing.T) {
 												Ellipsis: &parse.TypeInfo{
 													TypeName:         "Field",
 													ExternalTypeName: "functions.Field",
+													PackageName:      "functions",
+													PackagePath:      "github.com/gocloud9/gen-tool/pkg/parse/_testdata/functions",
 													IsStruct:         true,
 													IsType:           true,
 													TypeOf:           &parse.TypeInfo{IsStruct: true},
@@ -630,11 +713,14 @@ func TestParser_ParseDirectory(t *testing.T) {
 			},
 			want: &parse.Results{
 				Packages: map[string]*parse.PackageInfo{
-					"interfaces": {
+					"github.com/gocloud9/gen-tool/pkg/parse/_testdata/interfaces": {
 						Name:         "interfaces",
+						Path:         "github.com/gocloud9/gen-tool/pkg/parse/_testdata/interfaces",
+						Module:       &parse.ModuleInfo{Path: "github.com/gocloud9/gen-tool", GoVersion: "1.24.10", Main: true},
 						Constants:    map[string]*parse.ConstantInfo{},
 						DefinedTypes: map[string]*parse.DefinedTypeInfo{},
 						Aliases:      map[string]*parse.AliasTypeInfo{},
+						Enums:        map[string]*parse.EnumInfo{},
 						Structs: map[string]*parse.StructInfo{
 							"TestStruct": {
 								Name:           "TestStruct",
@@ -686,6 +772,8 @@ func TestParser_ParseDirectory(t *testing.T) {
 																		Slice: &parse.TypeInfo{
 																			TypeName:         "TestInterface",
 																			ExternalTypeName: "interfaces.TestInterface",
+																			PackageName:      "interfaces",
+																			PackagePath:      "github.com/gocloud9/gen-tool/pkg/parse/_testdata/interfaces",
 																			IsInterface:      true,
 																			IsType:           true,
 																			TypeOf:           &parse.TypeInfo{IsInterface: true},
@@ -706,6 +794,8 @@ func TestParser_ParseDirectory(t *testing.T) {
 																		MapValue: &parse.TypeInfo{
 																			TypeName:         "TestStruct",
 																			ExternalTypeName: "interfaces.TestStruct",
+																			PackageName:      "interfaces",
+																			PackagePath:      "github.com/gocloud9/gen-tool/pkg/parse/_testdata/interfaces",
 																			IsStruct:         true,
 																			IsType:           true,
 																			TypeOf:           &parse.TypeInfo{IsStruct: true},
@@ -757,12 +847,15 @@ func TestParser_ParseDirectory(t *testing.T) {
 			},
 			want: &parse.Results{
 				Packages: map[string]*parse.PackageInfo{
-					"globals": {
+					"github.com/gocloud9/gen-tool/pkg/parse/_testdata/globals": {
 						Name:    "globals",
+						Path:    "github.com/gocloud9/gen-tool/pkg/parse/_testdata/globals",
+						Module:  &parse.ModuleInfo{Path: "github.com/gocloud9/gen-tool", GoVersion: "1.24.10", Main: true},
 						Structs: map[string]*parse.StructInfo{},
 						DefinedTypes: map[string]*parse.DefinedTypeInfo{
 							"MyString": {
 								Name:    "MyString",
+								Methods: map[string]*parse.FuncInfo{},
 								Markers: map[string]string{},
 								TypeInfo: &parse.TypeInfo{
 									TypeName:         "string",
@@ -771,6 +864,32 @@ func TestParser_ParseDirectory(t *testing.T) {
 							},
 						},
 						Aliases: map[string]*parse.AliasTypeInfo{},
+						Enums: map[string]*parse.EnumInfo{
+							"MyString": {
+								Name:    "MyString",
+								Markers: map[string]string{},
+								Type: &parse.DefinedTypeInfo{
+									Name:    "MyString",
+									Methods: map[string]*parse.FuncInfo{},
+									Markers: map[string]string{},
+									TypeInfo: &parse.TypeInfo{
+										TypeName:         "string",
+										ExternalTypeName: "string",
+									},
+								},
+								Values: []*parse.ConstantInfo{
+									{
+										Name:        "myStringType",
+										Markers:     map[string]string{},
+										TypeName:    "MyString",
+										Value:       `"test"`,
+										Kind:        parse.ConstantString,
+										ExactValue:  `"test"`,
+										StringValue: "test",
+									},
+								},
+							},
+						},
 						Constants: map[string]*parse.ConstantInfo{
 							"myConstant": {
 								Name: "myConstant",
@@ -778,14 +897,21 @@ func TestParser_ParseDirectory(t *testing.T) {
 									"+Foo": "true",
 									"+Bar": "123",
 								},
-								TypeName: "string",
-								Value:    `"test"`,
+								TypeName:    "string",
+								Value:       `"test"`,
+								Kind:        parse.ConstantString,
+								IsUntyped:   true,
+								ExactValue:  `"test"`,
+								StringValue: "test",
 							},
 							"myStringType": {
-								Name:     "myStringType",
-								Markers:  map[string]string{},
-								TypeName: "MyString",
-								Value:    `"test"`,
+								Name:        "myStringType",
+								Markers:     map[string]string{},
+								TypeName:    "MyString",
+								Value:       `"test"`,
+								Kind:        parse.ConstantString,
+								ExactValue:  `"test"`,
+								StringValue: "test",
 							},
 						},
 						Vars: map[string]*parse.VarInfo{
@@ -820,6 +946,41 @@ func TestParser_ParseDirectory(t *testing.T) {
 									},
 								},
 							},
+							"myFunc2": {
+								Name: "myFunc2",
+								Markers: map[string]string{
+									"+Foo": "true",
+									"+Bar": "123",
+								},
+								TypeInfo: &parse.TypeInfo{
+									TypeName:         "[]func(string) error",
+									ExternalTypeName: "[]func(string) error",
+									IsSlice:          true,
+									Slice: &parse.TypeInfo{
+										TypeName:         "func(string) error",
+										ExternalTypeName: "func(string) error",
+										IsFunc:           true,
+										Func: &parse.FuncDefInfo{
+											Params: []*parse.ParamInfo{
+												{
+													TypeInfo: &parse.TypeInfo{
+														TypeName:         "string",
+														ExternalTypeName: "string",
+													},
+												},
+											},
+											Results: []*parse.ResultInfo{
+												{
+													TypeInfo: &parse.TypeInfo{
+														TypeName:         "error",
+														ExternalTypeName: "error",
+													},
+												},
+											},
+										},
+									},
+								},
+							},
 						},
 						Functions:  map[string]*parse.FuncInfo{},
 						Interfaces: map[string]*parse.InterfaceInfo{},
@@ -834,14 +995,17 @@ func TestParser_ParseDirectory(t *testing.T) {
 			},
 			want: &parse.Results{
 				Packages: map[string]*parse.PackageInfo{
-					"embedded": {
+					"github.com/gocloud9/gen-tool/pkg/parse/_testdata/embedded": {
 						Name:      "embedded",
+						Path:      "github.com/gocloud9/gen-tool/pkg/parse/_testdata/embedded",
+						Module:    &parse.ModuleInfo{Path: "github.com/gocloud9/gen-tool", GoVersion: "1.24.10", Main: true},
 						Constants: map[string]*parse.ConstantInfo{},
 						Vars:      map[string]*parse.VarInfo{},
 						Functions: map[string]*parse.FuncInfo{},
 						DefinedTypes: map[string]*parse.DefinedTypeInfo{
 							"ParentStruct": {
 								Name:    "ParentStruct",
+								Methods: map[string]*parse.FuncInfo{},
 								Markers: map[string]string{},
 								TypeInfo: &parse.TypeInfo{
 									TypeName:         "func()",
@@ -855,6 +1019,7 @@ func TestParser_ParseDirectory(t *testing.T) {
 							},
 						},
 						Aliases: map[string]*parse.AliasTypeInfo{},
+						Enums:   map[string]*parse.EnumInfo{},
 						Interfaces: map[string]*parse.InterfaceInfo{
 							"ParentInterface": {
 								Name:          "ParentInterface",
@@ -872,15 +1037,14 @@ func TestParser_ParseDirectory(t *testing.T) {
 								EmbeddedTypes: map[string]*parse.EmbeddedTypeInfo{
 									"ParentInterface": {
 										Name:     "ParentInterface",
-										TypeName: "ParentInterface",
-										Markers:  map[string]string{"+Bar": "123", "+Foo": "true"},
-									},
-									"Parent": {
-										Name:     "Parent",
-										TypeName: "Parent",
+										TypeInfo: &parse.TypeInfo{TypeName: "ParentInterface", ExternalTypeName: "embedded.ParentInterface", PackageName: "embedded", PackagePath: "github.com/gocloud9/gen-tool/pkg/parse/_testdata/embedded", IsInterface: true, IsType: true, TypeOf: &parse.TypeInfo{IsInterface: true}},
 										Markers:  map[string]string{"+Bar": "123", "+Foo": "true"},
 									},
 								},
+								// Parent is a struct, it restricts the type set instead of embedding methods
+								Constraints: []*parse.TypeInfo{
+									{TypeName: "Parent", ExternalTypeName: "embedded.Parent", PackageName: "embedded", PackagePath: "github.com/gocloud9/gen-tool/pkg/parse/_testdata/embedded", IsStruct: true, IsType: true, TypeOf: &parse.TypeInfo{IsStruct: true}},
+								},
 							},
 						},
 						Structs: map[string]*parse.StructInfo{
@@ -894,14 +1058,16 @@ func TestParser_ParseDirectory(t *testing.T) {
 								EmbeddedFields: map[string]parse.EmbeddedFieldInfo{
 									"ParentInterface": {
 										Name:     "ParentInterface",
-										TypeName: "ParentInterface",
+										TypeInfo: &parse.TypeInfo{TypeName: "ParentInterface", ExternalTypeName: "embedded.ParentInterface", PackageName: "embedded", PackagePath: "github.com/gocloud9/gen-tool/pkg/parse/_testdata/embedded", IsInterface: true, IsType: true, TypeOf: &parse.TypeInfo{IsInterface: true}},
 										Markers:  map[string]string{"+Bar": "123", "+Foo": "true"},
+										RawTag:   `yaml:",inline"`,
 										Tags:     map[string][]string{"yaml": {"", "inline"}},
 									},
 									"Parent": {
 										Name:     "Parent",
-										TypeName: "Parent",
+										TypeInfo: &parse.TypeInfo{TypeName: "Parent", ExternalTypeName: "embedded.Parent", PackageName: "embedded", PackagePath: "github.com/gocloud9/gen-tool/pkg/parse/_testdata/embedded", IsStruct: true, IsType: true, TypeOf: &parse.TypeInfo{IsStruct: true}},
 										Markers:  map[string]string{"+Bar": "123", "+Foo": "true"},
+										RawTag:   `yaml:",inline"`,
 										Tags:     map[string][]string{"yaml": {"", "inline"}},
 									},
 								},
@@ -926,8 +1092,10 @@ func TestParser_ParseDirectory(t *testing.T) {
 			},
 			want: &parse.Results{
 				Packages: map[string]*parse.PackageInfo{
-					"typing": {
-						Name: "typing",
+					"github.com/gocloud9/gen-tool/pkg/parse/_testdata/typing": {
+						Name:   "typing",
+						Path:   "github.com/gocloud9/gen-tool/pkg/parse/_testdata/typing",
+						Module: &parse.ModuleInfo{Path: "github.com/gocloud9/gen-tool", GoVersion: "1.24.10", Main: true},
 						Structs: map[string]*parse.StructInfo{
 							"AStruct": {
 								Name: "AStruct",
@@ -943,6 +1111,8 @@ func TestParser_ParseDirectory(t *testing.T) {
 										TypeInfo: &parse.TypeInfo{
 											TypeName:         "StringType",
 											ExternalTypeName: "typing.StringType",
+											PackageName:      "typing",
+											PackagePath:      "github.com/gocloud9/gen-tool/pkg/parse/_testdata/typing",
 											IsType:           true,
 											TypeOf: &parse.TypeInfo{
 												TypeName:         "string",
@@ -957,6 +1127,8 @@ func TestParser_ParseDirectory(t *testing.T) {
 										TypeInfo: &parse.TypeInfo{
 											TypeName:         "IntType",
 											ExternalTypeName: "typing.IntType",
+											PackageName:      "typing",
+											PackagePath:      "github.com/gocloud9/gen-tool/pkg/parse/_testdata/typing",
 											IsType:           true,
 											TypeOf: &parse.TypeInfo{
 												TypeName:         "int",
@@ -976,6 +1148,7 @@ func TestParser_ParseDirectory(t *testing.T) {
 						DefinedTypes: map[string]*parse.DefinedTypeInfo{
 							"StringType": {
 								Name:    "StringType",
+								Methods: map[string]*parse.FuncInfo{},
 								Markers: map[string]string{"+Bar": "123", "+Foo": "true"},
 								TypeInfo: &parse.TypeInfo{
 									TypeName:         "string",
@@ -984,35 +1157,42 @@ func TestParser_ParseDirectory(t *testing.T) {
 							},
 							"AStructType": {
 								Name:     "AStructType",
+								Methods:  map[string]*parse.FuncInfo{},
 								Markers:  map[string]string{"+Bar": "123", "+Foo": "true"},
-								TypeInfo: &parse.TypeInfo{TypeName: "AStruct", ExternalTypeName: "typing.AStruct", IsStruct: true, IsType: true, TypeOf: &parse.TypeInfo{IsStruct: true}},
+								TypeInfo: &parse.TypeInfo{TypeName: "AStruct", ExternalTypeName: "typing.AStruct", PackageName: "typing", PackagePath: "github.com/gocloud9/gen-tool/pkg/parse/_testdata/typing", IsStruct: true, IsType: true, TypeOf: &parse.TypeInfo{IsStruct: true}},
 							},
 							"IntType": {
 								Name:     "IntType",
+								Methods:  map[string]*parse.FuncInfo{},
 								Markers:  map[string]string{"+Bar": "123", "+Foo": "true"},
 								TypeInfo: &parse.TypeInfo{TypeName: "int", ExternalTypeName: "int"},
 							},
 							"OfAType": {
 								Name:    "OfAType",
+								Methods: map[string]*parse.FuncInfo{},
 								Markers: map[string]string{"+Bar": "123", "+Foo": "true"},
 								TypeInfo: &parse.TypeInfo{
 									TypeName:         "IntType",
 									ExternalTypeName: "typing.IntType",
+									PackageName:      "typing",
+									PackagePath:      "github.com/gocloud9/gen-tool/pkg/parse/_testdata/typing",
 									IsType:           true,
 									TypeOf:           &parse.TypeInfo{TypeName: "int", ExternalTypeName: "int"},
 								},
 							},
 							"SliceType": {
 								Name:    "SliceType",
+								Methods: map[string]*parse.FuncInfo{},
 								Markers: map[string]string{"+Bar": "123", "+Foo": "true"},
 								TypeInfo: &parse.TypeInfo{
 									TypeName:         "[]AStruct",
 									ExternalTypeName: "[]typing.AStruct",
 									IsSlice:          true,
-									Slice:            &parse.TypeInfo{TypeName: "AStruct", ExternalTypeName: "typing.AStruct", IsStruct: true, IsType: true, TypeOf: &parse.TypeInfo{IsStruct: true}},
+									Slice:            &parse.TypeInfo{TypeName: "AStruct", ExternalTypeName: "typing.AStruct", PackageName: "typing", PackagePath: "github.com/gocloud9/gen-tool/pkg/parse/_testdata/typing", IsStruct: true, IsType: true, TypeOf: &parse.TypeInfo{IsStruct: true}},
 								},
 							},
 						},
+						Enums: map[string]*parse.EnumInfo{},
 						Aliases: map[string]*parse.AliasTypeInfo{
 							"AliasStringType": {
 								Name:     "AliasStringType",
@@ -1024,28 +1204,2573 @@ func TestParser_ParseDirectory(t *testing.T) {
 				},
 			},
 		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			p := &parse.Parser{}
-			wd, err := os.Getwd()
-			if err != nil {
-				t.Fatalf("failed to get working directory: %v", err)
-			}
-
-			path := filepath.Join(wd, tt.args.path)
-
-			got, err := p.ParseDirectory(parse.Options{Path: path, SkipFilesWithContentsRegex: tt.args.skipFiles})
-			if (err != nil) != tt.wantErr {
-				t.Errorf("ParseDirectory() error = %v, wantErr %v", err, tt.wantErr)
-				return
-			}
-
-			if tt.want != nil {
-				if diff := cmp.Diff(tt.want, got); diff != "" {
-					t.Errorf("mismatch (-want +got):\n%s", diff)
-				}
+		{
+			name: "generics",
+			args: args{
+				path: "./_testdata/generics",
+			},
+			want: &parse.Results{
+				Packages: map[string]*parse.PackageInfo{
+					"github.com/gocloud9/gen-tool/pkg/parse/_testdata/generics": {
+						Name:   "generics",
+						Path:   "github.com/gocloud9/gen-tool/pkg/parse/_testdata/generics",
+						Module: &parse.ModuleInfo{Path: "github.com/gocloud9/gen-tool", GoVersion: "1.24.10", Main: true},
+						Structs: map[string]*parse.StructInfo{
+							"Holder": {
+								Name:    "Holder",
+								Markers: map[string]string{},
+								Fields: map[string]*parse.FieldInfo{
+									"Ints": {
+										Name:    "Ints",
+										Markers: map[string]string{},
+										Tags:    map[string][]string{},
+										TypeInfo: &parse.TypeInfo{
+											TypeName:         "List[int]",
+											ExternalTypeName: "generics.List[int]",
+											PackageName:      "generics",
+											PackagePath:      "github.com/gocloud9/gen-tool/pkg/parse/_testdata/generics",
+											IsSlice:          true,
+											IsType:           true,
+											IsGeneric:        true,
+											Slice: &parse.TypeInfo{
+												TypeName:         "int",
+												ExternalTypeName: "int",
+											},
+											TypeOf: &parse.TypeInfo{
+												TypeName:         "[]int",
+												ExternalTypeName: "[]int",
+												IsSlice:          true,
+												Slice: &parse.TypeInfo{
+													TypeName:         "int",
+													ExternalTypeName: "int",
+												},
+											},
+											TypeArgs: []*parse.TypeInfo{
+												{
+													TypeName:         "int",
+													ExternalTypeName: "int",
+												},
+											},
+										},
+									},
+									"Pairs": {
+										Name:    "Pairs",
+										Markers: map[string]string{},
+										Tags:    map[string][]string{},
+										TypeInfo: &parse.TypeInfo{
+											TypeName:         "Pair[string, int]",
+											ExternalTypeName: "generics.Pair[string, int]",
+											PackageName:      "generics",
+											PackagePath:      "github.com/gocloud9/gen-tool/pkg/parse/_testdata/generics",
+											IsStruct:         true,
+											IsType:           true,
+											IsGeneric:        true,
+											TypeOf: &parse.TypeInfo{
+												IsStruct: true,
+											},
+											TypeArgs: []*parse.TypeInfo{
+												{
+													TypeName:         "string",
+													ExternalTypeName: "string",
+												},
+												{
+													TypeName:         "int",
+													ExternalTypeName: "int",
+												},
+											},
+										},
+									},
+								},
+								Methods:        map[string]*parse.FuncInfo{},
+								EmbeddedFields: map[string]parse.EmbeddedFieldInfo{},
+							},
+							"Page": {
+								Name: "Page",
+								Markers: map[string]string{
+									"+Bar": "123",
+									"+Foo": "true",
+								},
+								TypeParams: []*parse.TypeParamInfo{
+									{
+										Name: "T",
+										Constraint: &parse.TypeInfo{
+											TypeName:         "any",
+											ExternalTypeName: "any",
+										},
+									},
+								},
+								Fields: map[string]*parse.FieldInfo{
+									"Items": {
+										Name:    "Items",
+										Markers: map[string]string{},
+										Tags:    map[string][]string{},
+										TypeInfo: &parse.TypeInfo{
+											TypeName:         "[]T",
+											ExternalTypeName: "[]T",
+											IsSlice:          true,
+											Slice: &parse.TypeInfo{
+												TypeName:         "T",
+												ExternalTypeName: "T",
+												IsTypeParam:      true,
+											},
+										},
+									},
+									"Next": {
+										Name:    "Next",
+										Markers: map[string]string{},
+										Tags:    map[string][]string{},
+										TypeInfo: &parse.TypeInfo{
+											TypeName:         "*Page[T]",
+											ExternalTypeName: "*generics.Page[T]",
+											IsPointer:        true,
+											Pointer: &parse.TypeInfo{
+												TypeName:         "Page[T]",
+												ExternalTypeName: "generics.Page[T]",
+												PackageName:      "generics",
+												PackagePath:      "github.com/gocloud9/gen-tool/pkg/parse/_testdata/generics",
+												IsStruct:         true,
+												IsType:           true,
+												IsGeneric:        true,
+												IsRecursive:      true,
+												TypeOf: &parse.TypeInfo{
+													IsStruct: true,
+												},
+												TypeArgs: []*parse.TypeInfo{
+													{
+														TypeName:         "T",
+														ExternalTypeName: "T",
+														IsTypeParam:      true,
+													},
+												},
+											},
+										},
+									},
+								},
+								Methods:        map[string]*parse.FuncInfo{},
+								EmbeddedFields: map[string]parse.EmbeddedFieldInfo{},
+							},
+							"Pair": {
+								Name:    "Pair",
+								Markers: map[string]string{},
+								TypeParams: []*parse.TypeParamInfo{
+									{
+										Name: "K",
+										Constraint: &parse.TypeInfo{
+											TypeName:         "comparable",
+											ExternalTypeName: "comparable",
+										},
+									},
+									{
+										Name: "V",
+										Constraint: &parse.TypeInfo{
+											TypeName:         "any",
+											ExternalTypeName: "any",
+										},
+									},
+								},
+								Fields: map[string]*parse.FieldInfo{
+									"Key": {
+										Name:    "Key",
+										Markers: map[string]string{},
+										Tags:    map[string][]string{},
+										TypeInfo: &parse.TypeInfo{
+											TypeName:         "K",
+											ExternalTypeName: "K",
+											IsTypeParam:      true,
+										},
+									},
+									"Value": {
+										Name:    "Value",
+										Markers: map[string]string{},
+										Tags:    map[string][]string{},
+										TypeInfo: &parse.TypeInfo{
+											TypeName:         "V",
+											ExternalTypeName: "V",
+											IsTypeParam:      true,
+										},
+									},
+								},
+								Methods:        map[string]*parse.FuncInfo{},
+								EmbeddedFields: map[string]parse.EmbeddedFieldInfo{},
+							},
+						},
+						Constants: map[string]*parse.ConstantInfo{},
+						Functions: map[string]*parse.FuncInfo{
+							"Sum": {
+								Name:    "Sum",
+								Markers: map[string]string{},
+								TypeParams: []*parse.TypeParamInfo{
+									{
+										Name: "N",
+										Constraint: &parse.TypeInfo{
+											TypeName:         "Number",
+											ExternalTypeName: "generics.Number",
+											PackageName:      "generics",
+											PackagePath:      "github.com/gocloud9/gen-tool/pkg/parse/_testdata/generics",
+											IsInterface:      true,
+											IsType:           true,
+											TypeOf: &parse.TypeInfo{
+												IsInterface: true,
+											},
+										},
+									},
+								},
+								FuncDefInfo: &parse.FuncDefInfo{
+									Params: []*parse.ParamInfo{
+										{
+											TypeInfo: &parse.TypeInfo{
+												TypeName:         "List[N]",
+												ExternalTypeName: "generics.List[N]",
+												PackageName:      "generics",
+												PackagePath:      "github.com/gocloud9/gen-tool/pkg/parse/_testdata/generics",
+												IsSlice:          true,
+												IsType:           true,
+												IsGeneric:        true,
+												Slice: &parse.TypeInfo{
+													TypeName:         "N",
+													ExternalTypeName: "N",
+													IsTypeParam:      true,
+												},
+												TypeOf: &parse.TypeInfo{
+													TypeName:         "[]N",
+													ExternalTypeName: "[]N",
+													IsSlice:          true,
+													Slice: &parse.TypeInfo{
+														TypeName:         "N",
+														ExternalTypeName: "N",
+														IsTypeParam:      true,
+													},
+												},
+												TypeArgs: []*parse.TypeInfo{
+													{
+														TypeName:         "N",
+														ExternalTypeName: "N",
+														IsTypeParam:      true,
+													},
+												},
+											},
+											Name: "in",
+										},
+									},
+									Results: []*parse.ResultInfo{
+										{
+											TypeInfo: &parse.TypeInfo{
+												TypeName:         "N",
+												ExternalTypeName: "N",
+												IsTypeParam:      true,
+											},
+										},
+									},
+								},
+							},
+						},
+						Interfaces: map[string]*parse.InterfaceInfo{
+							"Container": {
+								Name:    "Container",
+								Markers: map[string]string{},
+								TypeParams: []*parse.TypeParamInfo{
+									{
+										Name: "T",
+										Constraint: &parse.TypeInfo{
+											TypeName:         "any",
+											ExternalTypeName: "any",
+										},
+									},
+								},
+								Methods: map[string]*parse.FuncInfo{
+									"Get": {
+										Name:    "Get",
+										Markers: map[string]string{},
+										FuncDefInfo: &parse.FuncDefInfo{
+											Params: []*parse.ParamInfo{},
+											Results: []*parse.ResultInfo{
+												{
+													TypeInfo: &parse.TypeInfo{
+														TypeName:         "T",
+														ExternalTypeName: "T",
+														IsTypeParam:      true,
+													},
+												},
+											},
+										},
+									},
+								},
+								EmbeddedTypes: map[string]*parse.EmbeddedTypeInfo{},
+							},
+							"Integer": {
+								Name:          "Integer",
+								Markers:       map[string]string{},
+								Methods:       map[string]*parse.FuncInfo{},
+								EmbeddedTypes: map[string]*parse.EmbeddedTypeInfo{},
+								Constraints: []*parse.TypeInfo{
+									{
+										TypeName:         "int",
+										ExternalTypeName: "int",
+									},
+								},
+							},
+							"Bytes": {
+								Name:          "Bytes",
+								Markers:       map[string]string{},
+								Methods:       map[string]*parse.FuncInfo{},
+								EmbeddedTypes: map[string]*parse.EmbeddedTypeInfo{},
+								Constraints: []*parse.TypeInfo{
+									{
+										TypeName:         "[]byte",
+										ExternalTypeName: "[]byte",
+										IsSlice:          true,
+										Slice: &parse.TypeInfo{
+											TypeName:         "byte",
+											ExternalTypeName: "byte",
+										},
+									},
+								},
+							},
+							"Ints": {
+								Name:          "Ints",
+								Markers:       map[string]string{},
+								Methods:       map[string]*parse.FuncInfo{},
+								EmbeddedTypes: map[string]*parse.EmbeddedTypeInfo{},
+								Constraints: []*parse.TypeInfo{
+									{
+										TypeName:         "List[int]",
+										ExternalTypeName: "generics.List[int]",
+										PackageName:      "generics",
+										PackagePath:      "github.com/gocloud9/gen-tool/pkg/parse/_testdata/generics",
+										IsSlice:          true,
+										IsType:           true,
+										IsGeneric:        true,
+										Slice: &parse.TypeInfo{
+											TypeName:         "int",
+											ExternalTypeName: "int",
+										},
+										TypeOf: &parse.TypeInfo{
+											TypeName:         "[]int",
+											ExternalTypeName: "[]int",
+											IsSlice:          true,
+											Slice: &parse.TypeInfo{
+												TypeName:         "int",
+												ExternalTypeName: "int",
+											},
+										},
+										TypeArgs: []*parse.TypeInfo{
+											{
+												TypeName:         "int",
+												ExternalTypeName: "int",
+											},
+										},
+									},
+								},
+							},
+							"Number": {
+								Name:          "Number",
+								Markers:       map[string]string{},
+								Methods:       map[string]*parse.FuncInfo{},
+								EmbeddedTypes: map[string]*parse.EmbeddedTypeInfo{},
+								Constraints: []*parse.TypeInfo{
+									{
+										TypeName:         "~int | ~int64 | float64",
+										ExternalTypeName: "~int | ~int64 | float64",
+										IsUnion:          true,
+										Union: []*parse.TypeInfo{
+											{
+												TypeName:         "~int",
+												ExternalTypeName: "~int",
+												IsApproximate:    true,
+											},
+											{
+												TypeName:         "~int64",
+												ExternalTypeName: "~int64",
+												IsApproximate:    true,
+											},
+											{
+												TypeName:         "float64",
+												ExternalTypeName: "float64",
+											},
+										},
+									},
+								},
+							},
+						},
+						Vars: map[string]*parse.VarInfo{},
+						DefinedTypes: map[string]*parse.DefinedTypeInfo{
+							"List": {
+								Name:    "List",
+								Methods: map[string]*parse.FuncInfo{},
+								Markers: map[string]string{},
+								TypeParams: []*parse.TypeParamInfo{
+									{
+										Name: "T",
+										Constraint: &parse.TypeInfo{
+											TypeName:         "any",
+											ExternalTypeName: "any",
+										},
+									},
+								},
+								TypeInfo: &parse.TypeInfo{
+									TypeName:         "[]T",
+									ExternalTypeName: "[]T",
+									IsSlice:          true,
+									Slice: &parse.TypeInfo{
+										TypeName:         "T",
+										ExternalTypeName: "T",
+										IsTypeParam:      true,
+									},
+								},
+							},
+						},
+						Aliases: map[string]*parse.AliasTypeInfo{},
+						Enums:   map[string]*parse.EnumInfo{},
+					},
+				},
+			},
+		},
+		{
+			name: "sibling files and dot imports",
+			args: args{
+				path: "./_testdata/resolve",
+			},
+			want: &parse.Results{
+				Packages: map[string]*parse.PackageInfo{
+					"github.com/gocloud9/gen-tool/pkg/parse/_testdata/resolve": {
+						Name:   "resolve",
+						Path:   "github.com/gocloud9/gen-tool/pkg/parse/_testdata/resolve",
+						Module: &parse.ModuleInfo{Path: "github.com/gocloud9/gen-tool", GoVersion: "1.24.10", Main: true},
+						Structs: map[string]*parse.StructInfo{
+							"Event": {
+								Name:    "Event",
+								Markers: map[string]string{},
+								Fields: map[string]*parse.FieldInfo{
+									"Kind": {
+										Name:    "Kind",
+										Markers: map[string]string{},
+										Tags:    map[string][]string{},
+										TypeInfo: &parse.TypeInfo{
+											TypeName:         "Kind",
+											ExternalTypeName: "resolve.Kind",
+											PackageName:      "resolve",
+											PackagePath:      "github.com/gocloud9/gen-tool/pkg/parse/_testdata/resolve",
+											IsType:           true,
+											TypeOf: &parse.TypeInfo{
+												TypeName:         "string",
+												ExternalTypeName: "string",
+											},
+										},
+									},
+									"Timeout": {
+										Name:    "Timeout",
+										Markers: map[string]string{},
+										Tags:    map[string][]string{},
+										TypeInfo: &parse.TypeInfo{
+											TypeName:         "time.Duration",
+											ExternalTypeName: "time.Duration",
+											PackageName:      "time",
+											PackagePath:      "time",
+											IsImported:       true,
+											IsType:           true,
+											ImportedType: &parse.ImportedTypeInfo{
+												TypeName:            "Duration",
+												ImportRaw:           "\"time\"",
+												PackagePath:         "time",
+												PackageDefaultAlias: "time",
+												Alias:               ".",
+											},
+											TypeOf: &parse.TypeInfo{
+												TypeName:         "int64",
+												ExternalTypeName: "int64",
+											},
+										},
+									},
+								},
+								Methods:        map[string]*parse.FuncInfo{},
+								EmbeddedFields: map[string]parse.EmbeddedFieldInfo{},
+							},
+						},
+						Constants:  map[string]*parse.ConstantInfo{},
+						Functions:  map[string]*parse.FuncInfo{},
+						Interfaces: map[string]*parse.InterfaceInfo{},
+						Vars:       map[string]*parse.VarInfo{},
+						DefinedTypes: map[string]*parse.DefinedTypeInfo{
+							"Kind": {
+								Name:    "Kind",
+								Methods: map[string]*parse.FuncInfo{},
+								Markers: map[string]string{},
+								TypeInfo: &parse.TypeInfo{
+									TypeName:         "string",
+									ExternalTypeName: "string",
+								},
+							},
+						},
+						Aliases: map[string]*parse.AliasTypeInfo{},
+						Enums:   map[string]*parse.EnumInfo{},
+					},
+				},
+			},
+		},
+		{
+			name: "literal types",
+			args: args{
+				path: "./_testdata/literals",
+			},
+			want: &parse.Results{
+				Packages: map[string]*parse.PackageInfo{
+					"github.com/gocloud9/gen-tool/pkg/parse/_testdata/literals": {
+						Name:   "literals",
+						Path:   "github.com/gocloud9/gen-tool/pkg/parse/_testdata/literals",
+						Module: &parse.ModuleInfo{Path: "github.com/gocloud9/gen-tool", GoVersion: "1.24.10", Main: true},
+						Structs: map[string]*parse.StructInfo{
+							"Literals": {
+								Name:    "Literals",
+								Markers: map[string]string{},
+								Fields: map[string]*parse.FieldInfo{
+									"Channels": {
+										Name:    "Channels",
+										Markers: map[string]string{},
+										Tags:    map[string][]string{},
+										TypeInfo: &parse.TypeInfo{
+											TypeName:         "chan (<-chan int)",
+											ExternalTypeName: "chan (<-chan int)",
+											IsChan:           true,
+											Chan: &parse.TypeInfo{
+												TypeName:         "<-chan int",
+												ExternalTypeName: "<-chan int",
+												IsChan:           true,
+												Chan: &parse.TypeInfo{
+													TypeName:         "int",
+													ExternalTypeName: "int",
+												},
+												ChanDir: "recv",
+											},
+											ChanDir: "both",
+										},
+									},
+									"Empty": {
+										Name:    "Empty",
+										Markers: map[string]string{},
+										Tags:    map[string][]string{},
+										TypeInfo: &parse.TypeInfo{
+											TypeName:         "struct{}",
+											ExternalTypeName: "struct{}",
+											IsStruct:         true,
+											Struct: &parse.StructInfo{
+												Markers:        map[string]string{},
+												Fields:         map[string]*parse.FieldInfo{},
+												Methods:        map[string]*parse.FuncInfo{},
+												EmbeddedFields: map[string]parse.EmbeddedFieldInfo{},
+											},
+										},
+									},
+									"Handler": {
+										Name:    "Handler",
+										Markers: map[string]string{},
+										Tags:    map[string][]string{},
+										TypeInfo: &parse.TypeInfo{
+											TypeName:         "interface{Reader; Close() error}",
+											ExternalTypeName: "interface{literals.Reader; Close() error}",
+											IsInterface:      true,
+											Interface: &parse.InterfaceInfo{
+												Markers: map[string]string{},
+												Methods: map[string]*parse.FuncInfo{
+													"Close": {
+														Name: "Close",
+														Markers: map[string]string{
+															"+Foo": "true",
+														},
+														FuncDefInfo: &parse.FuncDefInfo{
+															Params: []*parse.ParamInfo{},
+															Results: []*parse.ResultInfo{
+																{
+																	TypeInfo: &parse.TypeInfo{
+																		TypeName:         "error",
+																		ExternalTypeName: "error",
+																	},
+																},
+															},
+														},
+													},
+												},
+												EmbeddedTypes: map[string]*parse.EmbeddedTypeInfo{
+													"Reader": {
+														Name:     "Reader",
+														TypeInfo: &parse.TypeInfo{TypeName: "Reader", ExternalTypeName: "literals.Reader", PackageName: "literals", PackagePath: "github.com/gocloud9/gen-tool/pkg/parse/_testdata/literals", IsInterface: true, IsType: true, TypeOf: &parse.TypeInfo{IsInterface: true}},
+														Markers:  map[string]string{},
+													},
+												},
+											},
+										},
+									},
+									"Hash": {
+										Name:    "Hash",
+										Markers: map[string]string{},
+										Tags:    map[string][]string{},
+										TypeInfo: &parse.TypeInfo{
+											TypeName:         "[4]byte",
+											ExternalTypeName: "[4]byte",
+											IsArray:          true,
+											Array: &parse.TypeInfo{
+												TypeName:         "byte",
+												ExternalTypeName: "byte",
+											},
+											ArrayLen: 4,
+										},
+									},
+									"Inline": {
+										Name:    "Inline",
+										Markers: map[string]string{},
+										Tags:    map[string][]string{},
+										TypeInfo: &parse.TypeInfo{
+											TypeName:         "struct{Name string `json:\"name\"`; Reader}",
+											ExternalTypeName: "struct{Name string `json:\"name\"`; literals.Reader}",
+											IsStruct:         true,
+											Struct: &parse.StructInfo{
+												Markers: map[string]string{},
+												Fields: map[string]*parse.FieldInfo{
+													"Name": {
+														Name: "Name",
+														Markers: map[string]string{
+															"+Foo": "true",
+														},
+														RawTag: `json:"name"`,
+														Tags: map[string][]string{
+															"json": {
+																"name",
+															},
+														},
+														TypeInfo: &parse.TypeInfo{
+															TypeName:         "string",
+															ExternalTypeName: "string",
+														},
+													},
+												},
+												Methods: map[string]*parse.FuncInfo{},
+												EmbeddedFields: map[string]parse.EmbeddedFieldInfo{
+													"Reader": {
+														Name:     "Reader",
+														TypeInfo: &parse.TypeInfo{TypeName: "Reader", ExternalTypeName: "literals.Reader", PackageName: "literals", PackagePath: "github.com/gocloud9/gen-tool/pkg/parse/_testdata/literals", IsInterface: true, IsType: true, TypeOf: &parse.TypeInfo{IsInterface: true}},
+														Markers:  map[string]string{},
+														Tags:     map[string][]string{},
+													},
+												},
+											},
+										},
+									},
+									"Matrix": {
+										Name:    "Matrix",
+										Markers: map[string]string{},
+										Tags:    map[string][]string{},
+										TypeInfo: &parse.TypeInfo{
+											TypeName:         "[2][3]int",
+											ExternalTypeName: "[2][3]int",
+											IsArray:          true,
+											Array: &parse.TypeInfo{
+												TypeName:         "[3]int",
+												ExternalTypeName: "[3]int",
+												IsArray:          true,
+												Array: &parse.TypeInfo{
+													TypeName:         "int",
+													ExternalTypeName: "int",
+												},
+												ArrayLen: 3,
+											},
+											ArrayLen: 2,
+										},
+									},
+									"Receive": {
+										Name:    "Receive",
+										Markers: map[string]string{},
+										Tags:    map[string][]string{},
+										TypeInfo: &parse.TypeInfo{
+											TypeName:         "<-chan string",
+											ExternalTypeName: "<-chan string",
+											IsChan:           true,
+											Chan: &parse.TypeInfo{
+												TypeName:         "string",
+												ExternalTypeName: "string",
+											},
+											ChanDir: "recv",
+										},
+									},
+									"Send": {
+										Name:    "Send",
+										Markers: map[string]string{},
+										Tags:    map[string][]string{},
+										TypeInfo: &parse.TypeInfo{
+											TypeName:         "chan<- string",
+											ExternalTypeName: "chan<- string",
+											IsChan:           true,
+											Chan: &parse.TypeInfo{
+												TypeName:         "string",
+												ExternalTypeName: "string",
+											},
+											ChanDir: "send",
+										},
+									},
+								},
+								Methods:        map[string]*parse.FuncInfo{},
+								EmbeddedFields: map[string]parse.EmbeddedFieldInfo{},
+							},
+						},
+						Constants: map[string]*parse.ConstantInfo{},
+						Functions: map[string]*parse.FuncInfo{},
+						Interfaces: map[string]*parse.InterfaceInfo{
+							"Reader": {
+								Name:    "Reader",
+								Markers: map[string]string{},
+								Methods: map[string]*parse.FuncInfo{
+									"Read": {
+										Name:    "Read",
+										Markers: map[string]string{},
+										FuncDefInfo: &parse.FuncDefInfo{
+											Params: []*parse.ParamInfo{
+												{
+													TypeInfo: &parse.TypeInfo{
+														TypeName:         "[]byte",
+														ExternalTypeName: "[]byte",
+														IsSlice:          true,
+														Slice: &parse.TypeInfo{
+															TypeName:         "byte",
+															ExternalTypeName: "byte",
+														},
+													},
+													Name: "p",
+												},
+											},
+											Results: []*parse.ResultInfo{
+												{
+													TypeInfo: &parse.TypeInfo{
+														TypeName:         "int",
+														ExternalTypeName: "int",
+													},
+													Name: "n",
+												},
+												{
+													TypeInfo: &parse.TypeInfo{
+														TypeName:         "error",
+														ExternalTypeName: "error",
+													},
+													Name: "err",
+												},
+											},
+										},
+									},
+								},
+								EmbeddedTypes: map[string]*parse.EmbeddedTypeInfo{},
+							},
+						},
+						Vars:         map[string]*parse.VarInfo{},
+						DefinedTypes: map[string]*parse.DefinedTypeInfo{},
+						Aliases:      map[string]*parse.AliasTypeInfo{},
+						Enums:        map[string]*parse.EnumInfo{},
+					},
+				},
+			},
+		},
+		{
+			name: "var and const declarations",
+			args: args{
+				path: "./_testdata/values",
+			},
+			want: &parse.Results{
+				Packages: map[string]*parse.PackageInfo{
+					"github.com/gocloud9/gen-tool/pkg/parse/_testdata/values": {
+						Name: "values",
+						Path: "github.com/gocloud9/gen-tool/pkg/parse/_testdata/values",
+						Module: &parse.ModuleInfo{
+							Path:      "github.com/gocloud9/gen-tool",
+							GoVersion: "1.24.10",
+							Main:      true,
+						},
+						Structs: map[string]*parse.StructInfo{},
+						Constants: map[string]*parse.ConstantInfo{
+							"KindA": {
+								Name:       "KindA",
+								TypeName:   "Kind",
+								Markers:    map[string]string{},
+								Value:      "iota",
+								Kind:       "int",
+								ExactValue: "0",
+								IntExact:   true,
+							},
+							"KindB": {
+								Name:       "KindB",
+								TypeName:   "Kind",
+								Markers:    map[string]string{},
+								Value:      "iota",
+								Kind:       "int",
+								ExactValue: "1",
+								IntValue:   1,
+								IntExact:   true,
+								FloatValue: 1,
+							},
+							"KindC": {
+								Name:     "KindC",
+								TypeName: "Kind",
+								Markers: map[string]string{
+									"+Bar": "123",
+								},
+								Value:      "iota",
+								Kind:       "int",
+								ExactValue: "2",
+								IntValue:   2,
+								IntExact:   true,
+								FloatValue: 2,
+							},
+							"Ratio": {
+								Name:       "Ratio",
+								TypeName:   "float64",
+								Markers:    map[string]string{},
+								Value:      "1.5",
+								Kind:       "float",
+								IsUntyped:  true,
+								ExactValue: "1.5",
+								FloatValue: 1.5,
+							},
+							"Scale": {
+								Name:       "Scale",
+								TypeName:   "int",
+								Markers:    map[string]string{},
+								Value:      "2",
+								Kind:       "int",
+								IsUntyped:  true,
+								ExactValue: "2",
+								IntValue:   2,
+								IntExact:   true,
+								FloatValue: 2,
+							},
+						},
+						Functions: map[string]*parse.FuncInfo{
+							"local": {
+								Name:    "local",
+								Markers: map[string]string{},
+								FuncDefInfo: &parse.FuncDefInfo{
+									Params:  []*parse.ParamInfo{},
+									Results: []*parse.ResultInfo{},
+								},
+							},
+						},
+						Interfaces: map[string]*parse.InterfaceInfo{},
+						Vars: map[string]*parse.VarInfo{
+							"a": {
+								Name:    "a",
+								Markers: map[string]string{},
+								TypeInfo: &parse.TypeInfo{
+									TypeName:         "int",
+									ExternalTypeName: "int",
+								},
+							},
+							"b": {
+								Name:    "b",
+								Markers: map[string]string{},
+								TypeInfo: &parse.TypeInfo{
+									TypeName:         "int",
+									ExternalTypeName: "int",
+								},
+							},
+							"builder": {
+								Name:    "builder",
+								Markers: map[string]string{},
+								TypeInfo: &parse.TypeInfo{
+									TypeName:         "*strings.Builder",
+									ExternalTypeName: "*strings.Builder",
+									IsPointer:        true,
+									Pointer: &parse.TypeInfo{
+										TypeName:         "strings.Builder",
+										ExternalTypeName: "strings.Builder",
+										PackageName:      "strings",
+										PackagePath:      "strings",
+										IsStruct:         true,
+										IsImported:       true,
+										IsType:           true,
+										ImportedType: &parse.ImportedTypeInfo{
+											TypeName:            "Builder",
+											ImportRaw:           "\"strings\"",
+											PackagePath:         "strings",
+											PackageDefaultAlias: "strings",
+										},
+										TypeOf: &parse.TypeInfo{
+											IsStruct: true,
+										},
+									},
+								},
+							},
+						},
+						DefinedTypes: map[string]*parse.DefinedTypeInfo{
+							"Kind": {
+								Name:    "Kind",
+								Methods: map[string]*parse.FuncInfo{},
+								Markers: map[string]string{},
+								TypeInfo: &parse.TypeInfo{
+									TypeName:         "int",
+									ExternalTypeName: "int",
+								},
+							},
+						},
+						Aliases: map[string]*parse.AliasTypeInfo{},
+						Enums: map[string]*parse.EnumInfo{
+							"Kind": {
+								Name:    "Kind",
+								Markers: map[string]string{},
+								Type: &parse.DefinedTypeInfo{
+									Name:    "Kind",
+									Methods: map[string]*parse.FuncInfo{},
+									Markers: map[string]string{},
+									TypeInfo: &parse.TypeInfo{
+										TypeName:         "int",
+										ExternalTypeName: "int",
+									},
+								},
+								Values: []*parse.ConstantInfo{
+									{
+										Name:       "KindA",
+										TypeName:   "Kind",
+										Markers:    map[string]string{},
+										Value:      "iota",
+										Kind:       "int",
+										ExactValue: "0",
+										IntExact:   true,
+									},
+									{
+										Name:       "KindB",
+										TypeName:   "Kind",
+										Markers:    map[string]string{},
+										Value:      "iota",
+										Kind:       "int",
+										ExactValue: "1",
+										IntValue:   1,
+										IntExact:   true,
+										FloatValue: 1,
+									},
+									{
+										Name:     "KindC",
+										TypeName: "Kind",
+										Markers: map[string]string{
+											"+Bar": "123",
+										},
+										Value:      "iota",
+										Kind:       "int",
+										ExactValue: "2",
+										IntValue:   2,
+										IntExact:   true,
+										FloatValue: 2,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &parse.Parser{}
+			wd, err := os.Getwd()
+			if err != nil {
+				t.Fatalf("failed to get working directory: %v", err)
+			}
+
+			path := filepath.Join(wd, tt.args.path)
+
+			got, err := p.ParseDirectory(parse.Options{Path: path, SkipFilesWithContentsRegex: tt.args.skipFiles})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseDirectory() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if tt.want != nil {
+				if diff := cmp.Diff(tt.want, got, ignoreLocationAndOrder); diff != "" {
+					t.Errorf("mismatch (-want +got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
+
+func TestParser_ParseDirectory_PackageLocation(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get working directory: %v", err)
+	}
+
+	path := filepath.Join(wd, "./_testdata/collision")
+
+	p := &parse.Parser{}
+	got, err := p.ParseDirectory(parse.Options{Path: path})
+	if err != nil {
+		t.Fatalf("ParseDirectory() error = %v", err)
+	}
+
+	tests := []struct {
+		pkgPath string
+		dir     string
+		structs []string
+	}{
+		{
+			pkgPath: "github.com/gocloud9/gen-tool/pkg/parse/_testdata/collision/v1",
+			dir:     filepath.Join(path, "v1"),
+			structs: []string{"Public"},
+		},
+		{
+			pkgPath: "github.com/gocloud9/gen-tool/pkg/parse/_testdata/collision/internal/v1",
+			dir:     filepath.Join(path, "internal", "v1"),
+			structs: []string{"Internal"},
+		},
+	}
+
+	if len(got.Packages) != len(tests) {
+		t.Fatalf("got %d packages, want %d", len(got.Packages), len(tests))
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pkgPath, func(t *testing.T) {
+			pi, ok := got.Packages[tt.pkgPath]
+			if !ok {
+				t.Fatalf("package %s not found", tt.pkgPath)
+			}
+
+			if pi.Name != "v1" || pi.Path != tt.pkgPath || pi.Dir != tt.dir {
+				t.Errorf("got Name=%q Path=%q Dir=%q", pi.Name, pi.Path, pi.Dir)
+			}
+
+			wantFiles := []string{filepath.Join(tt.dir, "v1.go")}
+			if diff := cmp.Diff(wantFiles, pi.GoFiles); diff != "" {
+				t.Errorf("GoFiles mismatch (-want +got):\n%s", diff)
+			}
+
+			for _, name := range tt.structs {
+				if _, ok := pi.Structs[name]; !ok {
+					t.Errorf("struct %s not found", name)
+				}
+			}
+		})
+	}
+}
+
+func TestParser_ParseDirectory_Positions(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get working directory: %v", err)
+	}
+
+	path := filepath.Join(wd, "./_testdata/positions")
+	filename := filepath.Join(path, "positions.go")
+
+	p := &parse.Parser{}
+	got, err := p.ParseDirectory(parse.Options{Path: path})
+	if err != nil {
+		t.Fatalf("ParseDirectory() error = %v", err)
+	}
+
+	pi := got.Packages["github.com/gocloud9/gen-tool/pkg/parse/_testdata/positions"]
+	if pi == nil {
+		t.Fatalf("package not found")
+	}
+
+	tests := []struct {
+		name string
+		got  parse.Position
+		want parse.Position
+	}{
+		{name: "constant", got: pi.Constants["Answer"].Position, want: parse.Position{Line: 3, Column: 7, EndLine: 3, EndColumn: 18}},
+		{name: "var", got: pi.Vars["Greeting"].Position, want: parse.Position{Line: 5, Column: 5, EndLine: 5, EndColumn: 23}},
+		{name: "defined type", got: pi.DefinedTypes["Kind"].Position, want: parse.Position{Line: 7, Column: 6, EndLine: 7, EndColumn: 17}},
+		{name: "alias", got: pi.Aliases["Alias"].Position, want: parse.Position{Line: 9, Column: 6, EndLine: 9, EndColumn: 18}},
+		{name: "interface", got: pi.Interfaces["Shape"].Position, want: parse.Position{Line: 11, Column: 6, EndLine: 13, EndColumn: 2}},
+		{name: "interface method", got: pi.Interfaces["Shape"].Methods["Area"].Position, want: parse.Position{Line: 12, Column: 2, EndLine: 12, EndColumn: 16}},
+		{name: "struct", got: pi.Structs["Square"].Position, want: parse.Position{Line: 15, Column: 6, EndLine: 18, EndColumn: 2}},
+		{name: "field", got: pi.Structs["Square"].Fields["Side"].Position, want: parse.Position{Line: 16, Column: 2, EndLine: 16, EndColumn: 14}},
+		{name: "embedded field", got: pi.Structs["Square"].EmbeddedFields["Kind"].Position, want: parse.Position{Line: 17, Column: 2, EndLine: 17, EndColumn: 6}},
+		{name: "function", got: pi.Functions["NewSquare"].Position, want: parse.Position{Line: 20, Column: 1, EndLine: 22, EndColumn: 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.want.Filename = filename
+			if diff := cmp.Diff(tt.want, tt.got, cmpopts.IgnoreFields(parse.Position{}, "Offset", "EndOffset")); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParser_ParseDirectory_DeclarationOrder(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get working directory: %v", err)
+	}
+
+	p := &parse.Parser{}
+	got, err := p.ParseDirectory(parse.Options{Path: filepath.Join(wd, "./_testdata/ordering")})
+	if err != nil {
+		t.Fatalf("ParseDirectory() error = %v", err)
+	}
+
+	pi := got.Packages["github.com/gocloud9/gen-tool/pkg/parse/_testdata/ordering"]
+	if pi == nil {
+		t.Fatalf("package not found")
+	}
+
+	record := pi.Structs["Record"]
+	service := pi.Interfaces["Service"]
+
+	tests := []struct {
+		name string
+		got  []string
+		want []string
+	}{
+		{
+			name: "constants",
+			got:  names(pi.ConstantList, func(ci *parse.ConstantInfo) string { return ci.Name }),
+			want: []string{"Zeta", "Alpha"},
+		},
+		{
+			name: "vars",
+			got:  names(pi.VarList, func(vi *parse.VarInfo) string { return vi.Name }),
+			want: []string{"Second", "First"},
+		},
+		{
+			name: "defined types",
+			got:  names(pi.DefinedTypeList, func(dti *parse.DefinedTypeInfo) string { return dti.Name }),
+			want: []string{"Beta", "Able"},
+		},
+		{
+			name: "functions",
+			got:  names(pi.FunctionList, func(fi *parse.FuncInfo) string { return fi.Name }),
+			want: []string{"Run"},
+		},
+		{
+			name: "struct fields",
+			got:  names(record.FieldList, func(fi *parse.FieldInfo) string { return fi.Name }),
+			want: []string{"ZField", "AField", "X", "B", "Mid"},
+		},
+		{
+			name: "struct methods",
+			got:  names(record.MethodList, func(fi *parse.FuncInfo) string { return fi.Name }),
+			want: []string{"Zed", "Abc"},
+		},
+		{
+			name: "interface methods",
+			got:  names(service.MethodList, func(fi *parse.FuncInfo) string { return fi.Name }),
+			want: []string{"Stop", "Start"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.want, tt.got); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func names[T any](values []T, name func(T) string) []string {
+	result := []string{}
+	for _, v := range values {
+		result = append(result, name(v))
+	}
+
+	return result
+}
+
+func TestParser_ParseDirectory_Enums(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get working directory: %v", err)
+	}
+
+	p := &parse.Parser{}
+	got, err := p.ParseDirectory(parse.Options{Path: filepath.Join(wd, "./_testdata/enums")})
+	if err != nil {
+		t.Fatalf("ParseDirectory() error = %v", err)
+	}
+
+	pi := got.Packages["github.com/gocloud9/gen-tool/pkg/parse/_testdata/enums"]
+	if pi == nil {
+		t.Fatalf("package not found")
+	}
+
+	type value struct {
+		Name       string
+		ExactValue string
+		IntValue   int64
+	}
+
+	want := map[string][]value{
+		"Color": {{"Red", "1", 1}, {"Green", "2", 2}, {"Blue", "3", 3}},
+		"Flag":  {{"FlagA", "1", 1}, {"FlagB", "2", 2}, {"FlagAB", "3", 3}},
+		"Level": {{"Debug", `"debug"`, 0}, {"Info", `"info"`, 0}},
+	}
+
+	if diff := cmp.Diff([]string{"Color", "Flag", "Level"}, names(pi.EnumList, func(ei *parse.EnumInfo) string { return ei.Name })); diff != "" {
+		t.Errorf("enum order mismatch (-want +got):\n%s", diff)
+	}
+
+	for _, ei := range pi.EnumList {
+		got := []value{}
+		for _, ci := range ei.Values {
+			got = append(got, value{ci.Name, ci.ExactValue, ci.IntValue})
+		}
+		if diff := cmp.Diff(want[ei.Name], got); diff != "" {
+			t.Errorf("%s values mismatch (-want +got):\n%s", ei.Name, diff)
+		}
+		if ei.Type != pi.DefinedTypes[ei.Name] {
+			t.Errorf("%s type does not point to the defined type", ei.Name)
+		}
+	}
+
+	size := pi.Constants["Size"]
+	if size.Kind != parse.ConstantInt || !size.IsUntyped || size.IntValue != 8 || size.Value != "1 << 3" {
+		t.Errorf("unexpected Size constant %+v", size)
+	}
+	if !size.IntExact {
+		t.Errorf("Size should fit an int64")
+	}
+
+	// Values overflowing an int64 are only kept exactly by ExactValue
+	for name, exact := range map[string]string{"Huge": "1267650600228229401496703205376", "MaxUint64": "18446744073709551615"} {
+		ci := pi.Constants[name]
+		if ci.Kind != parse.ConstantInt || ci.IntExact || ci.ExactValue != exact {
+			t.Errorf("unexpected %s constant %+v", name, ci)
+		}
+	}
+}
+
+func TestParser_ParseDirectory_Docs(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get working directory: %v", err)
+	}
+
+	const pkgPath = "github.com/gocloud9/gen-tool/pkg/parse/_testdata/docs"
+
+	p := &parse.Parser{}
+	got, err := p.ParseDirectory(parse.Options{Path: filepath.Join(wd, "./_testdata/docs"), MarkerPrefix: "+"})
+	if err != nil {
+		t.Fatalf("ParseDirectory() error = %v", err)
+	}
+
+	pi := got.Packages[pkgPath]
+	if pi == nil {
+		t.Fatalf("package not found")
+	}
+
+	type doc struct {
+		Doc     string
+		Markers map[string]string
+	}
+
+	tests := []struct {
+		name string
+		got  doc
+		want doc
+	}{
+		{"package", doc{pi.Doc, map[string]string{}}, doc{"Package docs has documented declarations.\n", map[string]string{}}},
+		{"struct", doc{pi.Structs["User"].Doc, pi.Structs["User"].Markers}, doc{"User is a customer account.\n", map[string]string{"kind": "model", "table": "users"}}},
+		{"field", doc{pi.Structs["User"].Fields["ID"].Doc, pi.Structs["User"].Fields["ID"].Markers}, doc{"ID is the primary key.\n", map[string]string{"readonly": ""}}},
+		{"field without markers", doc{pi.Structs["User"].Fields["Name"].Doc, pi.Structs["User"].Fields["Name"].Markers}, doc{"Name is shown to other users.\n", map[string]string{}}},
+		{"method", doc{pi.Structs["User"].Methods["Greet"].Doc, pi.Structs["User"].Methods["Greet"].Markers}, doc{"Greet returns a greeting.\n", map[string]string{}}},
+		{"defined type", doc{pi.DefinedTypes["Status"].Doc, pi.DefinedTypes["Status"].Markers}, doc{"Status of an account.\n", map[string]string{"enum": ""}}},
+		{"enum", doc{pi.Enums["Status"].Doc, pi.Enums["Status"].Markers}, doc{"Status of an account.\n", map[string]string{"enum": ""}}},
+		{"constant", doc{pi.Constants["Active"].Doc, pi.Constants["Active"].Markers}, doc{"Active accounts can sign in.\n", map[string]string{}}},
+		{"constant with only markers", doc{pi.Constants["Disabled"].Doc, pi.Constants["Disabled"].Markers}, doc{"", map[string]string{"deprecated": ""}}},
+		{"interface", doc{pi.Interfaces["Store"].Doc, pi.Interfaces["Store"].Markers}, doc{"Store persists users.\n", map[string]string{"kind": "store"}}},
+		{"interface method", doc{pi.Interfaces["Store"].Methods["Save"].Doc, pi.Interfaces["Store"].Methods["Save"].Markers}, doc{"Save stores the user.\n", map[string]string{}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.want, tt.got); diff != "" {
+				t.Errorf("doc mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	t.Run("without marker prefix", func(t *testing.T) {
+		got, err := p.ParseDirectory(parse.Options{Path: filepath.Join(wd, "./_testdata/docs")})
+		if err != nil {
+			t.Fatalf("ParseDirectory() error = %v", err)
+		}
+
+		si := got.Packages[pkgPath].Structs["User"]
+		want := doc{"", map[string]string{"User is a customer account.": "", "": "", "+kind": "model", "+table": "users"}}
+		if diff := cmp.Diff(want, doc{si.Doc, si.Markers}); diff != "" {
+			t.Errorf("doc mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
+
+func TestParser_ParseDirectory_Markers(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get working directory: %v", err)
+	}
+
+	p := &parse.Parser{}
+	got, err := p.ParseDirectory(parse.Options{Path: filepath.Join(wd, "./_testdata/markers"), MarkerPrefix: "+"})
+	if err != nil {
+		t.Fatalf("ParseDirectory() error = %v", err)
+	}
+
+	si := got.Packages["github.com/gocloud9/gen-tool/pkg/parse/_testdata/markers"].Structs["Config"]
+
+	type marker struct {
+		Name     string
+		Path     []string
+		ArgNames []string
+		Args     map[string]any
+	}
+
+	values := func(markers []*parse.Marker) []marker {
+		result := []marker{}
+		for _, m := range markers {
+			args := map[string]any{}
+			for name, v := range m.Args {
+				args[name] = v.Value()
+			}
+			result = append(result, marker{m.Name, m.Path, m.ArgNames, args})
+		}
+		return result
+	}
+
+	want := []marker{
+		{
+			Name:     "gen:builder",
+			Path:     []string{"gen", "builder"},
+			ArgNames: []string{"name", "skip", "max", "strict"},
+			Args:     map[string]any{"name": "Foo", "skip": []any{"A", "B"}, "max": int64(10), "strict": true},
+		},
+		{Name: "validate:enum", Path: []string{"validate", "enum"}, ArgNames: []string{""}, Args: map[string]any{"": "a"}},
+		{Name: "validate:enum", Path: []string{"validate", "enum"}, ArgNames: []string{""}, Args: map[string]any{"": "b"}},
+		{
+			Name:     "kubebuilder:printcolumn",
+			Path:     []string{"kubebuilder", "printcolumn"},
+			ArgNames: []string{"name", "type", "labels"},
+			Args:     map[string]any{"name": "Age", "type": "date", "labels": map[string]any{"app": "web", "tier": int64(2)}},
+		},
+		{Name: "sizes", Path: []string{"sizes"}, ArgNames: []string{""}, Args: map[string]any{"": []any{int64(1), int64(2), int64(3)}}},
+		{Name: "deprecated", Path: []string{"deprecated"}, ArgNames: []string{}, Args: map[string]any{}},
+	}
+
+	if diff := cmp.Diff(want, values(si.MarkerList)); diff != "" {
+		t.Errorf("markers mismatch (-want +got):\n%s", diff)
+	}
+
+	if diff := cmp.Diff(
+		[]marker{{Name: "default", Path: []string{"default"}, ArgNames: []string{""}, Args: map[string]any{"": "x, y"}}},
+		values(si.Fields["Name"].MarkerList),
+	); diff != "" {
+		t.Errorf("field markers mismatch (-want +got):\n%s", diff)
+	}
+
+	if si.Markers["validate:enum"] != "b" {
+		t.Errorf("Markers[validate:enum] = %q, want the last value", si.Markers["validate:enum"])
+	}
+
+	if pos := si.MarkerList[1].Position; pos.Line != 5 || pos.Column != 4 || pos.EndColumn != 20 {
+		t.Errorf("unexpected marker position %s", pos)
+	}
+}
+
+func TestParser_ParseDirectory_MarkerRegistry(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get working directory: %v", err)
+	}
+
+	registry := parse.NewMarkerRegistry()
+	err = registry.Register(
+		parse.MarkerDefinition{
+			Name:    "gen:builder",
+			Targets: []parse.MarkerTarget{parse.MarkerTargetStruct},
+			Args: []parse.MarkerArg{
+				{Name: "name", Kind: parse.MarkerValueString, Required: true},
+				{Name: "max", Kind: parse.MarkerValueInt},
+			},
+		},
+		parse.MarkerDefinition{
+			Name:    "validate:enum",
+			Targets: []parse.MarkerTarget{parse.MarkerTargetField},
+			Args:    []parse.MarkerArg{{Name: "", Kind: parse.MarkerValueList, Required: true}},
+		},
+		parse.MarkerDefinition{
+			Name:    "validate:max",
+			Targets: []parse.MarkerTarget{parse.MarkerTargetField},
+			Args:    []parse.MarkerArg{{Name: "", Kind: parse.MarkerValueInt}},
+		},
+	)
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	if err := registry.Register(parse.MarkerDefinition{Name: "gen:builder"}); err == nil {
+		t.Errorf("Register() of a duplicate marker should fail")
+	}
+
+	p := &parse.Parser{}
+	got, err := p.ParseDirectory(parse.Options{
+		Path:         filepath.Join(wd, "./_testdata/registry"),
+		MarkerPrefix: "+",
+		Markers:      registry,
+	})
+	if err != nil {
+		t.Fatalf("ParseDirectory() error = %v", err)
+	}
+
+	type diagnostic struct {
+		Severity parse.DiagnosticSeverity
+		Line     int
+		Message  string
+	}
+
+	diagnostics := []diagnostic{}
+	for _, d := range got.Diagnostics {
+		if d.Kind != parse.DiagnosticMarker || d.Package != "github.com/gocloud9/gen-tool/pkg/parse/_testdata/registry" {
+			t.Errorf("unexpected diagnostic %+v", d)
+		}
+		diagnostics = append(diagnostics, diagnostic{d.Severity, d.Position.Line, d.Message})
+	}
+
+	want := []diagnostic{
+		{parse.SeverityWarning, 4, `unknown marker "gen:buidler"`},
+		{parse.SeverityError, 6, `marker "gen:builder" is not allowed on a field`},
+		{parse.SeverityError, 10, `argument "" of marker "validate:max" must be int, got string`},
+		{parse.SeverityError, 13, `argument "" of marker "validate:enum" must be list, got map`},
+		{parse.SeverityError, 17, `marker "validate:enum" is not allowed on a func`},
+		{parse.SeverityError, 20, `missing required argument "name" of marker "gen:builder"`},
+		{parse.SeverityError, 22, `malformed marker "validate:max": unterminated { in {1`},
+	}
+	if diff := cmp.Diff(want, diagnostics); diff != "" {
+		t.Errorf("diagnostics mismatch (-want +got):\n%s", diff)
+	}
+
+	pi := got.Packages["github.com/gocloud9/gen-tool/pkg/parse/_testdata/registry"]
+	enum := pi.Structs["Config"].Fields["Level"].MarkerList[0]
+	if diff := cmp.Diff([]any{"a", "b"}, enum.Args[""].Value()); diff != "" {
+		t.Errorf("enum values mismatch (-want +got):\n%s", diff)
+	}
+
+	// Validation leaves the parsed markers as written, Normalize returns registered copies
+	builder := pi.Structs["Options"].MarkerList[0]
+	if builder.Name != "gen:builder:max" || builder.Args[""].IntValue != 3 {
+		t.Errorf("validated marker changed, got %s with %v", builder.Name, builder.ArgNames)
+	}
+	normalized, ok := registry.Normalize(builder)
+	if !ok || normalized.Name != "gen:builder" || normalized.Args["max"].IntValue != 3 {
+		t.Errorf("Normalize() of a single argument marker = %+v, %t", normalized, ok)
+	}
+	if builder.Name != "gen:builder:max" || len(builder.Path) != 3 {
+		t.Errorf("Normalize() changed the marker, got %s", builder.Name)
+	}
+
+	run := pi.Functions["Run"].MarkerList[0]
+	normalized, ok = registry.Normalize(run)
+	if diff := cmp.Diff([]any{"x"}, normalized.Args[""].Value()); !ok || diff != "" {
+		t.Errorf("Normalize() of a single value for a list mismatch (-want +got):\n%s", diff)
+	}
+	if run.Args[""].Kind != parse.MarkerValueString {
+		t.Errorf("Normalize() changed the argument to %s", run.Args[""].Kind)
+	}
+
+	if _, ok := registry.Normalize(pi.Structs["Config"].MarkerList[1]); ok {
+		t.Errorf("Normalize() of an unknown marker should fail")
+	}
+
+	// The same source gives the same markers with or without a registry
+	plain, err := p.ParseDirectory(parse.Options{
+		Path:         filepath.Join(wd, "./_testdata/registry"),
+		MarkerPrefix: "+",
+	})
+	if err != nil {
+		t.Fatalf("ParseDirectory() error = %v", err)
+	}
+	opts := cmp.Options{
+		cmpopts.IgnoreFields(parse.TypeInfo{}, "StructDecl", "InterfaceDecl", "DefinedTypeDecl", "AliasDecl", "Named"),
+		cmpopts.IgnoreUnexported(parse.Marker{}),
+	}
+	if diff := cmp.Diff(plain.Packages, got.Packages, opts); diff != "" {
+		t.Errorf("registry changed the results (-without +with):\n%s", diff)
+	}
+}
+
+func TestParser_ParseDirectory_Tags(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get working directory: %v", err)
+	}
+
+	p := &parse.Parser{}
+	got, err := p.ParseDirectory(parse.Options{Path: filepath.Join(wd, "./_testdata/tags")})
+	if err != nil {
+		t.Fatalf("ParseDirectory() error = %v", err)
+	}
+
+	si := got.Packages["github.com/gocloud9/gen-tool/pkg/parse/_testdata/tags"].Structs["User"]
+
+	tests := []struct {
+		field   string
+		raw     string
+		tagList parse.TagList
+		tags    map[string][]string
+	}{
+		{
+			field: "ID",
+			raw:   `json:"id,omitempty,string" db:"user_id"`,
+			tagList: parse.TagList{
+				{Key: "json", Value: "id,omitempty,string", Name: "id", Options: []string{"omitempty", "string"}},
+				{Key: "db", Value: "user_id", Name: "user_id", Options: []string{}},
+			},
+			tags: map[string][]string{"json": {"id", "omitempty", "string"}, "db": {"user_id"}},
+		},
+		{
+			field: "Role",
+			raw:   `validate:"oneof=admin member" description:"The user role"`,
+			tagList: parse.TagList{
+				{Key: "validate", Value: "oneof=admin member", Name: "oneof=admin member", Options: []string{}},
+				{Key: "description", Value: "The user role", Name: "The user role", Options: []string{}},
+			},
+			tags: map[string][]string{"validate": {"oneof=admin member"}, "description": {"The user role"}},
+		},
+		{
+			field:   "Email",
+			raw:     `json:"email"`,
+			tagList: parse.TagList{{Key: "json", Value: "email", Name: "email", Options: []string{}}},
+			tags:    map[string][]string{"json": {"email"}},
+		},
+		{
+			field: "Note",
+			raw:   `json:"-" json:"note"`,
+			tagList: parse.TagList{
+				{Key: "json", Value: "-", Name: "-", Options: []string{}},
+				{Key: "json", Value: "note", Name: "note", Options: []string{}},
+			},
+			tags: map[string][]string{"json": {"-"}},
+		},
+		{
+			field:   "Plain",
+			tagList: parse.TagList{},
+			tags:    map[string][]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			fi := si.Fields[tt.field]
+			if fi.RawTag != tt.raw {
+				t.Errorf("RawTag = %q, want %q", fi.RawTag, tt.raw)
+			}
+			if diff := cmp.Diff(tt.tagList, fi.TagList); diff != "" {
+				t.Errorf("TagList mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.tags, fi.Tags); diff != "" {
+				t.Errorf("Tags mismatch (-want +got):\n%s", diff)
+			}
+
+			for _, key := range fi.TagList.Keys() {
+				if want := reflect.StructTag(fi.RawTag).Get(key); fi.TagList.Get(key) != want {
+					t.Errorf("Get(%q) = %q, want %q", key, fi.TagList.Get(key), want)
+				}
+			}
+		})
+	}
+
+	inline := si.EmbeddedFields["Base"]
+	if ti, ok := inline.TagList.Lookup("yaml"); !ok || ti.Name != "" || !cmp.Equal(ti.Options, []string{"inline"}) {
+		t.Errorf("unexpected embedded field tag %+v", ti)
+	}
+}
+
+func TestParser_ParseDirectory_Embedded(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get working directory: %v", err)
+	}
+
+	p := &parse.Parser{}
+	got, err := p.ParseDirectory(parse.Options{Path: filepath.Join(wd, "./_testdata/promoted"), IncludePromoted: true})
+	if err != nil {
+		t.Fatalf("ParseDirectory() error = %v", err)
+	}
+
+	pi := got.Packages["github.com/gocloud9/gen-tool/pkg/parse/_testdata/promoted"]
+	child := pi.Structs["Child"]
+
+	type embedded struct {
+		Name      string
+		TypeName  string
+		IsPointer bool
+		Package   string
+	}
+
+	embeddedFields := []embedded{}
+	for _, efi := range child.EmbeddedFieldList {
+		embeddedFields = append(embeddedFields, embedded{efi.Name, efi.TypeName, efi.IsPointer, efi.PackagePath})
+	}
+	wantFields := []embedded{
+		{"Parent", "*Parent", true, ""},
+		{"Reader", "io.Reader", false, "io"},
+		{"Mutex", "sync.Mutex", false, "sync"},
+		{"Base", "Base[string]", false, "github.com/gocloud9/gen-tool/pkg/parse/_testdata/promoted"},
+		{"Middle", "Middle", false, "github.com/gocloud9/gen-tool/pkg/parse/_testdata/promoted"},
+	}
+	if diff := cmp.Diff(wantFields, embeddedFields); diff != "" {
+		t.Errorf("embedded fields mismatch (-want +got):\n%s", diff)
+	}
+	if base := child.EmbeddedFields["Base"]; !base.IsGeneric || base.TypeArgs[0].TypeName != "string" {
+		t.Errorf("Base type args not recorded: %+v", base.TypeInfo)
+	}
+
+	embeddedTypes := []embedded{}
+	for _, eti := range pi.Interfaces["ReadLocker"].EmbeddedTypeList {
+		embeddedTypes = append(embeddedTypes, embedded{eti.Name, eti.TypeName, eti.IsPointer, eti.PackagePath})
+	}
+	wantTypes := []embedded{
+		{"Reader", "io.Reader", false, "io"},
+		{"Locker", "sync.Locker", false, "sync"},
+		{"Stringer", "Stringer", false, "github.com/gocloud9/gen-tool/pkg/parse/_testdata/promoted"},
+		{"Getter", "Getter[string]", false, "github.com/gocloud9/gen-tool/pkg/parse/_testdata/promoted"},
+	}
+	if diff := cmp.Diff(wantTypes, embeddedTypes); diff != "" {
+		t.Errorf("embedded types mismatch (-want +got):\n%s", diff)
+	}
+
+	// Embedded fields and embedded interface types are both keyed by name
+	if diff := cmp.Diff([]string{"Base", "Middle", "Mutex", "Parent", "Reader"}, slices.Sorted(maps.Keys(child.EmbeddedFields))); diff != "" {
+		t.Errorf("embedded field keys mismatch (-want +got):\n%s", diff)
+	}
+	wantKeys := []string{"Getter", "Locker", "Reader", "Stringer"}
+	if diff := cmp.Diff(wantKeys, slices.Sorted(maps.Keys(pi.Interfaces["ReadLocker"].EmbeddedTypes))); diff != "" {
+		t.Errorf("embedded type keys mismatch (-want +got):\n%s", diff)
+	}
+	inline := pi.Structs["Holder"].Fields["Locker"].Interface
+	if diff := cmp.Diff(wantKeys, slices.Sorted(maps.Keys(inline.EmbeddedTypes))); diff != "" {
+		t.Errorf("embedded type keys of the interface literal mismatch (-want +got):\n%s", diff)
+	}
+
+	type promoted struct {
+		Name     string
+		TypeName string
+		Path     []string
+	}
+
+	promotedFields := []promoted{}
+	for _, pfi := range child.PromotedFields {
+		promotedFields = append(promotedFields, promoted{pfi.Name, pfi.TypeName, pfi.Path})
+	}
+	// Name and ID are ambiguous at depth one, Age is shadowed and the sync.Mutex fields are unexported
+	wantPromotedFields := []promoted{
+		{"Value", "string", []string{"Base"}},
+		{"Inner", "Inner", []string{"Middle"}},
+		{"Depth", "int", []string{"Middle", "Inner"}},
+	}
+	if diff := cmp.Diff(wantPromotedFields, promotedFields); diff != "" {
+		t.Errorf("promoted fields mismatch (-want +got):\n%s", diff)
+	}
+
+	promotedMethods := []promoted{}
+	for _, pmi := range child.PromotedMethods {
+		promotedMethods = append(promotedMethods, promoted{pmi.Name, "", pmi.Path})
+	}
+	wantPromotedMethods := []promoted{
+		{"Describe", "", []string{"Parent"}},
+		{"Get", "", []string{"Base"}},
+		{"Lock", "", []string{"Mutex"}},
+		{"Read", "", []string{"Reader"}},
+		{"Rename", "", []string{"Parent"}},
+		{"TryLock", "", []string{"Mutex"}},
+		{"Unlock", "", []string{"Mutex"}},
+	}
+	if diff := cmp.Diff(wantPromotedMethods, promotedMethods); diff != "" {
+		t.Errorf("promoted methods mismatch (-want +got):\n%s", diff)
+	}
+
+	if describe := child.PromotedMethods[0]; describe.Markers["+Bar"] != "123" {
+		t.Errorf("promoted method does not reuse the declaration, markers %v", describe.Markers)
+	}
+	if middle := pi.Structs["Middle"]; len(middle.PromotedFields) != 1 || middle.PromotedFields[0].FieldInfo != pi.Structs["Inner"].Fields["Depth"] {
+		t.Errorf("promoted field does not reuse the declaration")
+	}
+}
+
+func TestParser_ParseDirectory_Methods(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get working directory: %v", err)
+	}
+
+	p := &parse.Parser{}
+	got, err := p.ParseDirectory(parse.Options{Path: filepath.Join(wd, "./_testdata/methods")})
+	if err != nil {
+		t.Fatalf("ParseDirectory() error = %v", err)
+	}
+
+	pi := got.Packages["github.com/gocloud9/gen-tool/pkg/parse/_testdata/methods"]
+	name := func(fi *parse.FuncInfo) string { return fi.Name }
+
+	type receiver struct {
+		VarName   string
+		TypeName  string
+		IsPointer bool
+		Receiver  string
+	}
+
+	receivers := map[string]receiver{}
+	for _, fi := range []*parse.FuncInfo{pi.Structs["Reference"].Methods["Get"], pi.Structs["Reference"].Methods["Update"], pi.Structs["Reference"].Methods["Static"], pi.Structs["List"].Methods["Push"], pi.DefinedTypes["Color"].Methods["Set"]} {
+		receivers[fi.Name] = receiver{fi.ReceiverVarName, fi.ReceiverType.TypeName, fi.IsPointerReceiver, fi.Receiver}
+	}
+	wantReceivers := map[string]receiver{
+		"Get":    {"r", "Reference", false, "Reference"},
+		"Update": {"r", "Reference", true, "*Reference"},
+		"Static": {"", "Reference", false, "Reference"},
+		"Push":   {"l", "List[T]", true, "*List[T]"},
+		"Set":    {"c", "Color", true, "*Color"},
+	}
+	if diff := cmp.Diff(wantReceivers, receivers); diff != "" {
+		t.Errorf("receivers mismatch (-want +got):\n%s", diff)
+	}
+
+	tests := []struct {
+		name         string
+		methods      []*parse.FuncInfo
+		valueMethods []*parse.FuncInfo
+		ptrMethods   []*parse.FuncInfo
+		want         [3][]string
+	}{
+		{
+			name:         "struct with promoted methods",
+			methods:      pi.Structs["Reference"].MethodList,
+			valueMethods: pi.Structs["Reference"].ValueMethodSet,
+			ptrMethods:   pi.Structs["Reference"].PointerMethodSet,
+			want:         [3][]string{{"Update", "Get", "Static"}, {"Get", "Hello", "Static"}, {"Bye", "Get", "Hello", "Static", "Update"}},
+		},
+		{
+			name:         "generic struct",
+			methods:      pi.Structs["List"].MethodList,
+			valueMethods: pi.Structs["List"].ValueMethodSet,
+			ptrMethods:   pi.Structs["List"].PointerMethodSet,
+			want:         [3][]string{{"Push", "Len"}, {"Len"}, {"Len", "Push"}},
+		},
+		{
+			name:         "defined type",
+			methods:      pi.DefinedTypes["Color"].MethodList,
+			valueMethods: pi.DefinedTypes["Color"].ValueMethodSet,
+			ptrMethods:   pi.DefinedTypes["Color"].PointerMethodSet,
+			want:         [3][]string{{"String", "Set", "Name"}, {"Name", "String"}, {"Name", "Set", "String"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := [3][]string{names(tt.methods, name), names(tt.valueMethods, name), names(tt.ptrMethods, name)}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("methods mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	if diff := cmp.Diff([]string{"String", "Len"}, names(pi.FunctionList, name)); diff != "" {
+		t.Errorf("functions mismatch (-want +got):\n%s", diff)
+	}
+
+	if pi.Structs["Reference"].ValueMethodSet[0] != pi.Structs["Reference"].Methods["Get"] {
+		t.Errorf("method set does not reuse the declared method")
+	}
+	if _, ok := pi.Structs["Hidden"].Methods["Close"]; !ok {
+		t.Errorf("Hidden.Close not attached to its receiver")
+	}
+
+	// Methods of a receiver whose file is skipped are not attached, and not functions either
+	skipped, err := p.ParseDirectory(parse.Options{
+		Path:                       filepath.Join(wd, "./_testdata/methods"),
+		SkipFilesWithContentsRegex: []*regexp.Regexp{regexp.MustCompile(`DO NOT EDIT`)},
+	})
+	if err != nil {
+		t.Fatalf("ParseDirectory() error = %v", err)
+	}
+	pi = skipped.Packages["github.com/gocloud9/gen-tool/pkg/parse/_testdata/methods"]
+	if diff := cmp.Diff([]string{"String", "Len"}, names(pi.FunctionList, name)); diff != "" {
+		t.Errorf("functions with a skipped receiver mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"Close"}, names(pi.UnattachedMethods, name)); diff != "" {
+		t.Errorf("unattached methods mismatch (-want +got):\n%s", diff)
+	}
+	if fi := pi.UnattachedMethods[0]; !fi.IsReceiver || fi.ReciverName != "Hidden" {
+		t.Errorf("Hidden.Close receiver not recorded, got %+v", fi)
+	}
+}
+
+func TestParser_ParseDirectory_Implementations(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get working directory: %v", err)
+	}
+
+	p := &parse.Parser{}
+	got, err := p.ParseDirectory(parse.Options{Path: filepath.Join(wd, "./_testdata/implements")})
+	if err != nil {
+		t.Fatalf("ParseDirectory() error = %v", err)
+	}
+
+	const (
+		root   = "github.com/gocloud9/gen-tool/pkg/parse/_testdata/implements"
+		remote = "github.com/gocloud9/gen-tool/pkg/parse/_testdata/implements/remote"
+	)
+	implementer := func(impl *parse.ImplementationInfo) string {
+		name := impl.TypePackage + "." + impl.TypeName
+		if impl.Pointer {
+			return "*" + name
+		}
+		return name
+	}
+	iface := func(impl *parse.ImplementationInfo) string {
+		return impl.InterfacePackage + "." + impl.Interface.Name
+	}
+
+	tests := []struct {
+		name string
+		got  []string
+		want []string
+	}{
+		{
+			name: "implementers",
+			got:  names(got.Implementers[root+".Greeter"], implementer),
+			want: []string{root + ".Name", "*" + root + ".Pointer", root + ".Value", root + ".Wrapper", "*" + remote + ".Remote"},
+		},
+		{
+			name: "implementers of embedded interfaces",
+			got:  names(got.Implementers[root+".GreetCloser"], implementer),
+			want: []string{"*" + root + ".Pointer"},
+		},
+		{
+			name: "implements across packages",
+			got:  names(got.Implements[root+".Value"], iface),
+			want: []string{root + ".Greeter", remote + ".Namer"},
+		},
+		{
+			name: "implements with pointer receivers",
+			got:  names(got.Implements[root+".Pointer"], iface),
+			want: []string{root + ".Closer", root + ".GreetCloser", root + ".Greeter", remote + ".Namer"},
+		},
+		{
+			name: "ImplementersOf",
+			got:  names(got.ImplementersOf(got.Packages[remote].Interfaces["Namer"]), implementer),
+			want: []string{root + ".Name", "*" + root + ".Pointer", root + ".Value", root + ".Wrapper", "*" + remote + ".Remote"},
+		},
+		{
+			name: "InterfacesOf defined type",
+			got:  names(got.InterfacesOf(got.Packages[root].DefinedTypes["Name"]), iface),
+			want: []string{root + ".Greeter", remote + ".Namer"},
+		},
+		{
+			name: "constraint, generic and empty interfaces are skipped",
+			got:  names(append(append(got.Implementers[root+".Number"], got.Implementers[root+".Getter"]...), got.Implementers[root+".Empty"]...), implementer),
+			want: []string{},
+		},
+		{
+			name: "generic types are skipped",
+			got:  names(got.Implements[root+".Box"], iface),
+			want: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.want, tt.got); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	impl := got.Implements[root+".Wrapper"][0]
+	if impl.Struct != got.Packages[root].Structs["Wrapper"] || impl.Interface != got.Packages[root].Interfaces["Greeter"] || impl.DefinedType != nil {
+		t.Errorf("implementation does not reference the parsed infos: %+v", impl)
+	}
+}
+
+func TestParser_ParseDirectory_References(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get working directory: %v", err)
+	}
+
+	p := &parse.Parser{}
+	got, err := p.ParseDirectory(parse.Options{Path: filepath.Join(wd, "./_testdata/references")})
+	if err != nil {
+		t.Fatalf("ParseDirectory() error = %v", err)
+	}
+
+	pi := got.Packages["github.com/gocloud9/gen-tool/pkg/parse/_testdata/references"]
+	customer := got.Packages["github.com/gocloud9/gen-tool/pkg/parse/_testdata/references/customer"]
+	order := pi.Structs["Order"]
+	process := pi.Functions["Process"]
+
+	tests := []struct {
+		name string
+		got  any
+		want any
+	}{
+		{
+			name: "struct across packages",
+			got:  order.Fields["Customer"].StructDecl,
+			want: customer.Structs["Customer"],
+		},
+		{
+			name: "nested struct",
+			got:  order.Fields["Customer"].StructDecl.Fields["Address"].StructDecl,
+			want: customer.Structs["Address"],
+		},
+		{
+			name: "alias",
+			got:  order.Fields["Account"].AliasDecl,
+			want: customer.Aliases["Account"],
+		},
+		{
+			name: "aliased struct",
+			got:  order.Fields["Account"].TypeOf.StructDecl,
+			want: customer.Structs["Customer"],
+		},
+		{
+			name: "slice of pointers",
+			got:  order.Fields["Items"].Slice.Pointer.StructDecl,
+			want: pi.Structs["Item"],
+		},
+		{
+			name: "recursive reference",
+			got:  pi.Structs["Item"].Fields["Order"].Pointer.StructDecl,
+			want: order,
+		},
+		{
+			name: "defined type",
+			got:  order.Fields["Status"].DefinedTypeDecl,
+			want: customer.DefinedTypes["Status"],
+		},
+		{
+			name: "interface",
+			got:  order.Fields["Handler"].InterfaceDecl,
+			want: customer.Interfaces["Handler"],
+		},
+		{
+			name: "interface method parameter",
+			got:  customer.Interfaces["Handler"].Methods["Handle"].Params[0].Pointer.StructDecl,
+			want: customer.Structs["Customer"],
+		},
+		{
+			name: "function parameter",
+			got:  process.Params[0].Pointer.StructDecl,
+			want: order,
+		},
+		{
+			name: "function result",
+			got:  process.Results[0].MapValue.StructDecl,
+			want: customer.Structs["Customer"],
+		},
+		{
+			name: "type outside the results",
+			got:  order.Fields["Created"].StructDecl,
+			want: (*parse.StructInfo)(nil),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("got %p, want %p", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParser_ParseDirectory_TypeTable(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get working directory: %v", err)
+	}
+
+	p := &parse.Parser{}
+	got, err := p.ParseDirectory(parse.Options{Path: filepath.Join(wd, "./_testdata/recursive")})
+	if err != nil {
+		t.Fatalf("ParseDirectory() error = %v", err)
+	}
+
+	const pkg = "github.com/gocloud9/gen-tool/pkg/parse/_testdata/recursive."
+	pi := got.Packages["github.com/gocloud9/gen-tool/pkg/parse/_testdata/recursive"]
+
+	recursive := map[string]bool{}
+	for key, nti := range got.Types {
+		recursive[key] = nti.IsRecursive
+	}
+	wantRecursive := map[string]bool{
+		pkg + "List":    true,
+		pkg + "Node":    true,
+		pkg + "A":       true,
+		pkg + "B":       true,
+		pkg + "Tree":    true,
+		pkg + "Visitor": true,
+		pkg + "Leaf":    false,
+		pkg + "Holder":  false,
+		pkg + "Alias":   false,
+		"time.Time":     false,
+	}
+	if diff := cmp.Diff(wantRecursive, recursive); diff != "" {
+		t.Errorf("IsRecursive mismatch (-want +got):\n%s", diff)
+	}
+
+	node := got.Types[pkg+"Node"]
+	holder := pi.Structs["Holder"]
+
+	tests := []struct {
+		name string
+		got  any
+		want any
+	}{
+		{
+			name: "references share the entry",
+			got:  holder.Fields["Node"].Named,
+			want: node,
+		},
+		{
+			name: "references inside the entry point back to it",
+			got:  node.Underlying.Struct.Fields["Next"].Pointer.Named,
+			want: node,
+		},
+		{
+			name: "references inside the entry are not expanded",
+			got:  node.Underlying.Struct.Fields["Children"].MapValue.Pointer.TypeOf,
+			want: (*parse.TypeInfo)(nil),
+		},
+		{
+			name: "instantiations share the generic entry",
+			got:  holder.Fields["Tree"].Named,
+			want: got.Types[pkg+"Tree"],
+		},
+		{
+			name: "alias entry",
+			got:  got.Types[pkg+"Alias"].Underlying.Named,
+			want: node,
+		},
+		{
+			name: "types outside the results are not described",
+			got:  got.Types["time.Time"].Underlying,
+			want: (*parse.TypeInfo)(nil),
+		},
+		{
+			name: "recursive reference",
+			got:  holder.Fields["Node"].IsRecursive,
+			want: true,
+		},
+		{
+			name: "non recursive reference",
+			got:  holder.Fields["Leaf"].IsRecursive,
+			want: false,
+		},
+		{
+			name: "self referencing defined type",
+			got:  pi.DefinedTypes["List"].Slice.IsRecursive,
+			want: true,
+		},
+		{
+			name: "mutually recursive structs",
+			got:  pi.Structs["B"].Fields["As"].Slice.StructDecl.Fields["B"].Pointer.IsRecursive,
+			want: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("got %v, want %v", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParser_ParseDirectory_Build(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get working directory: %v", err)
+	}
+
+	const (
+		build = "github.com/gocloud9/gen-tool/pkg/parse/_testdata/build"
+		other = "github.com/gocloud9/gen-tool/pkg/parse/_testdata/build/other"
+	)
+
+	type pkg struct {
+		Structs []string
+		Build   parse.BuildInfo
+	}
+
+	tests := []struct {
+		name string
+		opts parse.Options
+		want map[string]pkg
+	}{
+		{
+			name: "target platform",
+			opts: parse.Options{GOOS: "windows", GOARCH: "arm64"},
+			want: map[string]pkg{
+				build: {Structs: []string{"Common", "WindowsOnly"}, Build: parse.BuildInfo{GOOS: "windows", GOARCH: "arm64", Tags: []string{}}},
+				other: {Structs: []string{"Other"}, Build: parse.BuildInfo{GOOS: "windows", GOARCH: "arm64", Tags: []string{}}},
+			},
+		},
+		{
+			name: "platform from env",
+			opts: parse.Options{GOARCH: "amd64", Env: []string{"GOOS=linux"}, Patterns: []string{"."}},
+			want: map[string]pkg{
+				build: {Structs: []string{"Common", "LinuxOnly"}, Build: parse.BuildInfo{GOOS: "linux", GOARCH: "amd64", Tags: []string{}}},
+			},
+		},
+		{
+			name: "build tags",
+			opts: parse.Options{GOOS: "linux", GOARCH: "amd64", BuildTags: []string{"integration"}, Patterns: []string{"."}},
+			want: map[string]pkg{
+				build: {Structs: []string{"Common", "LinuxOnly", "Integration"}, Build: parse.BuildInfo{GOOS: "linux", GOARCH: "amd64", Tags: []string{"integration"}}},
+			},
+		},
+		{
+			name: "tests",
+			opts: parse.Options{GOOS: "linux", GOARCH: "amd64", Tests: true},
+			want: map[string]pkg{
+				build:           {Structs: []string{"Common", "LinuxOnly", "TestHelper"}, Build: parse.BuildInfo{GOOS: "linux", GOARCH: "amd64", Tags: []string{}, Tests: true, IsTest: true}},
+				build + "_test": {Structs: []string{"External"}, Build: parse.BuildInfo{GOOS: "linux", GOARCH: "amd64", Tags: []string{}, Tests: true, IsTest: true}},
+				other:           {Structs: []string{"Other"}, Build: parse.BuildInfo{GOOS: "linux", GOARCH: "amd64", Tags: []string{}, Tests: true}},
+			},
+		},
+		{
+			name: "patterns",
+			opts: parse.Options{GOOS: "linux", GOARCH: "amd64", Patterns: []string{"./other"}},
+			want: map[string]pkg{
+				other: {Structs: []string{"Other"}, Build: parse.BuildInfo{GOOS: "linux", GOARCH: "amd64", Tags: []string{}}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.Path = filepath.Join(wd, "./_testdata/build")
+
+			p := &parse.Parser{}
+			results, err := p.ParseDirectory(tt.opts)
+			if err != nil {
+				t.Fatalf("ParseDirectory() error = %v", err)
+			}
+
+			got := map[string]pkg{}
+			for path, pi := range results.Packages {
+				got[path] = pkg{
+					Structs: names(pi.StructList, func(si *parse.StructInfo) string { return si.Name }),
+					Build:   *pi.Build,
+				}
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParser_ParseSource(t *testing.T) {
+	p := &parse.Parser{}
+	got, err := p.ParseSource(map[string]string{
+		"order.go": `package order
+
+import "source/customer"
+
+type Order struct {
+	Customer customer.Customer
+}
+`,
+		"customer/customer.go": `package customer
+
+type Customer struct {
+	Name string
+}
+`,
+	})
+	if err != nil {
+		t.Fatalf("ParseSource() error = %v", err)
+	}
+
+	order := got.Packages["source"]
+	customer := got.Packages["source/customer"]
+	if order == nil || customer == nil {
+		t.Fatalf("packages not found: %v", slices.Sorted(maps.Keys(got.Packages)))
+	}
+
+	if order.Module.Path != "source" {
+		t.Errorf("module path = %q, want %q", order.Module.Path, "source")
+	}
+	if got := filepath.Base(order.Structs["Order"].Position.Filename); got != "order.go" {
+		t.Errorf("filename = %q, want %q", got, "order.go")
+	}
+	if order.Structs["Order"].Fields["Customer"].StructDecl != customer.Structs["Customer"] {
+		t.Errorf("field type not linked to the declaration in the other package")
+	}
+
+	for _, name := range []string{"/tmp/a.go", "../a.go", "pkg/../../a.go", ""} {
+		if _, err := p.ParseSource(map[string]string{name: "package a\n"}); err == nil {
+			t.Errorf("ParseSource() of %q should fail", name)
+		}
+	}
+}
+
+func TestParser_ParseDirectory_Overlay(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get working directory: %v", err)
+	}
+
+	p := &parse.Parser{}
+	got, err := p.ParseDirectory(parse.Options{
+		Path: filepath.Join(wd, "./_testdata/simple"),
+		Overlay: map[string][]byte{
+			"extra.go": []byte("package simple\n\ntype Extra struct {\n\tUser User\n}\n"),
+		},
+	})
+	if err != nil {
+		t.Fatalf("ParseDirectory() error = %v", err)
+	}
+
+	pi := got.Packages["github.com/gocloud9/gen-tool/pkg/parse/_testdata/simple"]
+	if diff := cmp.Diff([]string{"Extra", "User"}, names(pi.StructList, func(si *parse.StructInfo) string { return si.Name })); diff != "" {
+		t.Errorf("structs mismatch (-want +got):\n%s", diff)
+	}
+	if pi.Structs["Extra"].Fields["User"].StructDecl != pi.Structs["User"] {
+		t.Errorf("overlay file not type checked with the package")
+	}
+}
+
+func TestParser_ParseDirectory_Errors(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get working directory: %v", err)
+	}
+
+	const broken = "github.com/gocloud9/gen-tool/pkg/parse/_testdata/broken/"
+
+	type diagnostic struct {
+		Kind     parse.DiagnosticKind
+		Severity parse.DiagnosticSeverity
+		Package  string
+		File     string
+		Line     int
+		Column   int
+	}
+	wantDiagnostics := []diagnostic{
+		{parse.DiagnosticType, parse.SeverityError, broken + "importerr", "importerr.go", 3, 8},
+		{parse.DiagnosticParse, parse.SeverityError, broken + "syntaxerr", "broken.go", 4, 14},
+		{parse.DiagnosticParse, parse.SeverityError, broken + "syntaxerr", "broken.go", 4, 14},
+		{parse.DiagnosticType, parse.SeverityError, broken + "typeerr", "typeerr.go", 4, 11},
+		{parse.DiagnosticType, parse.SeverityError, broken + "typeerr", "typeerr.go", 9, 9},
+	}
+
+	tests := []struct {
+		name            string
+		continueOnError bool
+		wantErr         error
+		wantPackages    []string
+	}{
+		{
+			name:         "broken packages are left out",
+			wantErr:      parse.ErrPackageErrors,
+			wantPackages: []string{broken + "fine"},
+		},
+		{
+			name:            "continue on error",
+			continueOnError: true,
+			wantPackages:    []string{broken + "fine", broken + "importerr", broken + "syntaxerr", broken + "typeerr"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &parse.Parser{}
+			got, err := p.ParseDirectory(parse.Options{
+				Path:            filepath.Join(wd, "./_testdata/broken"),
+				ContinueOnError: tt.continueOnError,
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ParseDirectory() error = %v, want %v", err, tt.wantErr)
+			}
+
+			diagnostics := []diagnostic{}
+			for _, d := range got.Diagnostics {
+				if d.Message == "" {
+					t.Errorf("diagnostic without a message: %v", d)
+				}
+				diagnostics = append(diagnostics, diagnostic{d.Kind, d.Severity, d.Package, filepath.Base(d.Position.Filename), d.Position.Line, d.Position.Column})
+			}
+			if diff := cmp.Diff(wantDiagnostics, diagnostics); diff != "" {
+				t.Errorf("diagnostics mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantPackages, slices.Sorted(maps.Keys(got.Packages))); diff != "" {
+				t.Errorf("packages mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	p := &parse.Parser{}
+	got, err := p.ParseDirectory(parse.Options{Path: filepath.Join(wd, "./_testdata/broken"), ContinueOnError: true})
+	if err != nil {
+		t.Fatalf("ParseDirectory() error = %v", err)
+	}
+	if diff := cmp.Diff([]string{"Broken", "Good"}, names(got.Packages[broken+"syntaxerr"].StructList, func(si *parse.StructInfo) string { return si.Name })); diff != "" {
+		t.Errorf("partial structs mismatch (-want +got):\n%s", diff)
+	}
+	if got := got.Packages[broken+"typeerr"].Structs["Order"].Fields["Name"].TypeName; got != "string" {
+		t.Errorf("field of a broken package = %q, want %q", got, "string")
+	}
+
+	_, err = p.ParseDirectory(parse.Options{Path: filepath.Join(wd, "./_testdata/missing")})
+	if err == nil || errors.Is(err, parse.ErrPackageErrors) {
+		t.Errorf("ParseDirectory() error = %v, want a load error", err)
+	}
+}
+
+func TestParser_ParseDirectory_Concurrency(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get working directory: %v", err)
+	}
+
+	p := &parse.Parser{}
+	parseWith := func(concurrency int) *parse.Results {
+		results, err := p.ParseDirectory(parse.Options{
+			Path:            filepath.Join(wd, "./_testdata/permutation"),
+			MarkerPrefix:    "+",
+			IncludePromoted: true,
+			Concurrency:     concurrency,
+		})
+		if err != nil {
+			t.Fatalf("ParseDirectory() error = %v", err)
+		}
+
+		return results
+	}
+
+	sequential := parseWith(1)
+	concurrent := parseWith(8)
+
+	opts := cmp.Options{
+		cmpopts.IgnoreFields(parse.TypeInfo{}, "StructDecl", "InterfaceDecl", "DefinedTypeDecl", "AliasDecl", "Named"),
+		cmpopts.IgnoreUnexported(parse.Marker{}),
+	}
+	if diff := cmp.Diff(sequential, concurrent, opts); diff != "" {
+		t.Errorf("concurrent results differ (-sequential +concurrent):\n%s", diff)
+	}
+
+	for path, pi := range concurrent.Packages {
+		for _, si := range pi.StructList {
+			for _, fi := range si.FieldList {
+				if fi.IsType && fi.Named != concurrent.Types[fi.Named.PackagePath+"."+fi.Named.Name] {
+					t.Errorf("%s.%s.%s does not point to the type table", path, si.Name, fi.Name)
+				}
+			}
+		}
+	}
+}
+
+func TestParser_ParseDirectory_Cache(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get working directory: %v", err)
+	}
+
+	path := filepath.Join(wd, "./_testdata/permutation")
+	cacheDir := t.TempDir()
+	p := &parse.Parser{}
+	parseWith := func(cacheDir string, overlay map[string][]byte) *parse.Results {
+		results, err := p.ParseDirectory(parse.Options{
+			Path:            path,
+			MarkerPrefix:    "+",
+			IncludePromoted: true,
+			CacheDir:        cacheDir,
+			Overlay:         overlay,
+		})
+		if err != nil {
+			t.Fatalf("ParseDirectory() error = %v", err)
+		}
+
+		return results
+	}
+	entries := func() []string {
+		files, err := filepath.Glob(filepath.Join(cacheDir, "*.json"))
+		if err != nil {
+			t.Fatalf("failed to list cache: %v", err)
+		}
+
+		return files
+	}
+
+	uncached := parseWith("", nil)
+	first := parseWith(cacheDir, nil)
+	if got := len(entries()); got != 2 {
+		t.Fatalf("cache entries = %d, want 2", got)
+	}
+	second := parseWith(cacheDir, nil)
+	if got := len(entries()); got != 2 {
+		t.Fatalf("cache entries after a second run = %d, want 2", got)
+	}
+
+	opts := cmp.Options{
+		cmpopts.IgnoreFields(parse.TypeInfo{}, "StructDecl", "InterfaceDecl", "DefinedTypeDecl", "AliasDecl", "Named"),
+		cmpopts.IgnoreUnexported(parse.Marker{}),
+	}
+	if diff := cmp.Diff(uncached, first, opts); diff != "" {
+		t.Errorf("first cached run differs (-uncached +cached):\n%s", diff)
+	}
+	if diff := cmp.Diff(uncached, second, opts); diff != "" {
+		t.Errorf("second cached run differs (-uncached +cached):\n%s", diff)
+	}
+
+	for path, pi := range second.Packages {
+		for _, si := range pi.StructList {
+			for _, fi := range si.FieldList {
+				if si.Fields[fi.Name] != fi {
+					t.Errorf("%s.%s.%s is not shared by Fields and FieldList", path, si.Name, fi.Name)
+				}
+				if fi.IsType && fi.Named != second.Types[fi.Named.PackagePath+"."+fi.Named.Name] {
+					t.Errorf("%s.%s.%s does not point to the type table", path, si.Name, fi.Name)
+				}
+			}
+		}
+	}
+
+	// Entries are read back instead of parsing the package again
+	for _, entry := range entries() {
+		data, err := os.ReadFile(entry)
+		if err != nil {
+			t.Fatalf("failed to read cache entry: %v", err)
+		}
+		data = bytes.Replace(data, []byte(`"GoFiles":[`), []byte(`"GoFiles":["cached.go",`), 1)
+		if err := os.WriteFile(entry, data, 0o644); err != nil {
+			t.Fatalf("failed to write cache entry: %v", err)
+		}
+	}
+	third := parseWith(cacheDir, nil)
+	for path, pi := range third.Packages {
+		if len(pi.GoFiles) == 0 || pi.GoFiles[0] != "cached.go" {
+			t.Errorf("%s was parsed again, GoFiles = %v", path, pi.GoFiles)
+		}
+	}
+
+	// A changed file only misses the entry of its package
+	source, err := os.ReadFile(filepath.Join(path, "package1", "package1.go"))
+	if err != nil {
+		t.Fatalf("failed to read source: %v", err)
+	}
+	changed := parseWith(cacheDir, map[string][]byte{
+		"package1/package1.go": append(source, []byte("\ntype Added struct{}\n")...),
+	})
+	if got := len(entries()); got != 3 {
+		t.Errorf("cache entries after a change = %d, want 3", got)
+	}
+	if _, ok := changed.Packages["permutation/package1"].Structs["Added"]; !ok {
+		t.Errorf("changed package was read from the cache")
+	}
+	if got := changed.Packages["permutation/package2"].GoFiles[0]; got != "cached.go" {
+		t.Errorf("unchanged package was parsed again, GoFiles[0] = %q", got)
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get working directory: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+	}{
+		{name: "permutation", path: "./_testdata/permutation"},
+		{name: "generics", path: "./_testdata/generics"},
+		{name: "recursive", path: "./_testdata/recursive"},
+		{name: "references", path: "./_testdata/references"},
+		{name: "implements", path: "./_testdata/implements"},
+		{name: "methods", path: "./_testdata/methods"},
+		{name: "registry", path: "./_testdata/registry"},
+	}
+
+	p := &parse.Parser{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := p.ParseDirectory(parse.Options{
+				Path:            filepath.Join(wd, tt.path),
+				MarkerPrefix:    "+",
+				IncludePromoted: true,
+			})
+			if err != nil {
+				t.Fatalf("ParseDirectory() error = %v", err)
+			}
+
+			data, err := parse.Marshal(results)
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			got, err := parse.Unmarshal(data)
+			if err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+
+			// The links between declarations are checked by marshaling again, cmp walks them slowly
+			opts := cmp.Options{
+				cmpopts.IgnoreFields(parse.TypeInfo{}, "StructDecl", "InterfaceDecl", "DefinedTypeDecl", "AliasDecl", "Named"),
+				cmpopts.IgnoreUnexported(parse.Marker{}),
+			}
+			if diff := cmp.Diff(results, got, opts); diff != "" {
+				t.Errorf("Unmarshal() mismatch (-parsed +unmarshaled):\n%s", diff)
+			}
+
+			again, err := parse.Marshal(got)
+			if err != nil {
+				t.Fatalf("Marshal() of the unmarshaled results error = %v", err)
+			}
+			if !bytes.Equal(data, again) {
+				t.Errorf("Marshal() of the unmarshaled results differs from the snapshot")
+			}
+
+			for path, pi := range got.Packages {
+				for _, si := range pi.StructList {
+					for _, fi := range si.FieldList {
+						if si.Fields[fi.Name] != fi {
+							t.Errorf("%s.%s.%s is not shared by Fields and FieldList", path, si.Name, fi.Name)
+						}
+						if fi.IsType && fi.Named != got.Types[fi.Named.PackagePath+"."+fi.Named.Name] {
+							t.Errorf("%s.%s.%s does not point to the type table", path, si.Name, fi.Name)
+						}
+						if fi.StructDecl != nil && fi.StructDecl != got.Packages[fi.PackagePath].Structs[fi.StructDecl.Name] {
+							t.Errorf("%s.%s.%s does not point to its declaration", path, si.Name, fi.Name)
+						}
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestUnmarshal_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		version bool
+	}{
+		{name: "not json", data: `{`},
+		{name: "other schema", data: `{"Schema":"other","Version":1,"Results":{}}`, version: true},
+		{name: "newer version", data: fmt.Sprintf(`{"Schema":%q,"Version":%d,"Results":{}}`, parse.ResultsSchema, parse.ResultsVersion+1), version: true},
+		{name: "no results", data: fmt.Sprintf(`{"Schema":%q,"Version":%d}`, parse.ResultsSchema, parse.ResultsVersion)},
+		{name: "dangling reference", data: fmt.Sprintf(`{"Schema":%q,"Version":%d,"Results":{"Types":{"a.B":{"$ref":1}}}}`, parse.ResultsSchema, parse.ResultsVersion)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parse.Unmarshal([]byte(tt.data))
+			if err == nil {
+				t.Fatalf("Unmarshal() should fail")
+			}
+			if got := errors.Is(err, parse.ErrSnapshotVersion); got != tt.version {
+				t.Errorf("Unmarshal() error = %v, ErrSnapshotVersion %t, want %t", err, got, tt.version)
 			}
 		})
 	}
@@ -0,0 +1,44 @@
+package parse
+
+import (
+	"fmt"
+	"go/token"
+)
+
+type Position struct {
+	Filename  string
+	Line      int
+	Column    int
+	Offset    int
+	EndLine   int
+	EndColumn int
+	EndOffset int
+}
+
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// String returns the start position in the file:line:column form used by the go tool
+func (p Position) String() string {
+	if !p.IsValid() {
+		return "-"
+	}
+
+	return fmt.Sprintf("%s:%d:%d", p.Filename, p.Line, p.Column)
+}
+
+func (fileCache fileCachedData) position(pos, end token.Pos) Position {
+	start := fileCache.fset.Position(pos)
+	stop := fileCache.fset.Position(end)
+
+	return Position{
+		Filename:  start.Filename,
+		Line:      start.Line,
+		Column:    start.Column,
+		Offset:    start.Offset,
+		EndLine:   stop.Line,
+		EndColumn: stop.Column,
+		EndOffset: stop.Offset,
+	}
+}
@@ -0,0 +1,189 @@
+package parse
+
+import (
+	"go/types"
+)
+
+// promoteMembers fills the promoted fields and methods of the package structs.
+// Shadowing and ambiguous selectors are resolved by go/types, members declared
+// in the package reuse their infos so markers and docs are kept.
+func promoteMembers(pi *PackageInfo, fileCache fileCachedData) {
+	for _, si := range pi.Structs {
+		obj, ok := fileCache.types.Scope().Lookup(si.Name).(*types.TypeName)
+		if !ok {
+			continue
+		}
+		named, ok := obj.Type().(*types.Named)
+		if !ok {
+			continue
+		}
+
+		si.PromotedFields = promotedFields(pi, named, fileCache)
+		si.PromotedMethods = promotedMethods(pi, named, fileCache)
+	}
+}
+
+func promotedFields(pi *PackageInfo, named *types.Named, fileCache fileCachedData) []*PromotedFieldInfo {
+	pfs := []*PromotedFieldInfo{}
+
+	seen := map[string]bool{}
+	for _, name := range embeddedFieldNames(named) {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		obj, index, _ := types.LookupFieldOrMethod(named, true, fileCache.types, name)
+		v, ok := obj.(*types.Var)
+		if !ok || len(index) < 2 {
+			continue
+		}
+
+		path, owner := selectionPath(named, index)
+		pfs = append(pfs, &PromotedFieldInfo{
+			FieldInfo: promotedFieldInfo(pi, owner, index[len(index)-1], v, fileCache),
+			Path:      path,
+		})
+	}
+
+	return pfs
+}
+
+func promotedMethods(pi *PackageInfo, named *types.Named, fileCache fileCachedData) []*PromotedMethodInfo {
+	pms := []*PromotedMethodInfo{}
+
+	methodSet := types.NewMethodSet(types.NewPointer(named))
+	for i := 0; i < methodSet.Len(); i++ {
+		selection := methodSet.At(i)
+		if len(selection.Index()) < 2 {
+			continue
+		}
+
+		path, owner := selectionPath(named, selection.Index())
+		pms = append(pms, &PromotedMethodInfo{
+			FuncInfo: promotedMethodInfo(pi, owner, selection.Obj().(*types.Func), fileCache),
+			Path:     path,
+		})
+	}
+
+	return pms
+}
+
+// embeddedFieldNames lists the names of the fields of the embedded structs, breadth first
+func embeddedFieldNames(named *types.Named) []string {
+	names := []string{}
+	visited := map[*types.TypeName]bool{named.Obj(): true}
+
+	level := []types.Type{named}
+	for len(level) > 0 {
+		next := []types.Type{}
+		for _, t := range level {
+			st, ok := derefStruct(t)
+			if !ok {
+				continue
+			}
+			for i := 0; i < st.NumFields(); i++ {
+				field := st.Field(i)
+				if t != types.Type(named) {
+					names = append(names, field.Name())
+				}
+				if !field.Embedded() {
+					continue
+				}
+				if n, ok := types.Unalias(derefType(field.Type())).(*types.Named); ok {
+					if visited[n.Origin().Obj()] {
+						continue
+					}
+					visited[n.Origin().Obj()] = true
+				}
+				next = append(next, field.Type())
+			}
+		}
+		level = next
+	}
+
+	return names
+}
+
+// selectionPath returns the embedded field names of the selection index and the type holding the member
+func selectionPath(named *types.Named, index []int) ([]string, types.Type) {
+	path := []string{}
+	var t types.Type = named
+	for _, i := range index[:len(index)-1] {
+		st, _ := derefStruct(t)
+		field := st.Field(i)
+		path = append(path, field.Name())
+		t = field.Type()
+	}
+
+	return path, derefType(t)
+}
+
+func promotedFieldInfo(pi *PackageInfo, owner types.Type, index int, v *types.Var, fileCache fileCachedData) *FieldInfo {
+	if si, ok := localStructInfo(pi, owner, fileCache); ok {
+		if fi, ok := si.Fields[v.Name()]; ok {
+			return fi
+		}
+	}
+
+	st, _ := derefStruct(owner)
+	tag := st.Tag(index)
+
+	return &FieldInfo{
+		Name:     v.Name(),
+		Position: fileCache.position(v.Pos(), v.Pos()),
+		Markers:  map[string]string{},
+		RawTag:   tag,
+		Tags:     parseRawTags(tag),
+		TagList:  parseTagList(tag),
+		TypeInfo: typeToTypeInfo(v.Type(), fileCache),
+	}
+}
+
+func promotedMethodInfo(pi *PackageInfo, owner types.Type, method *types.Func, fileCache fileCachedData) *FuncInfo {
+	if si, ok := localStructInfo(pi, owner, fileCache); ok {
+		if fi, ok := si.Methods[method.Name()]; ok {
+			return fi
+		}
+	}
+	if named, ok := types.Unalias(owner).(*types.Named); ok && named.Obj().Pkg() == fileCache.types {
+		if ii, ok := pi.Interfaces[named.Obj().Name()]; ok && named.TypeArgs().Len() == 0 {
+			if fi, ok := ii.Methods[method.Name()]; ok {
+				return fi
+			}
+		}
+	}
+
+	return &FuncInfo{
+		Name:        method.Name(),
+		Position:    fileCache.position(method.Pos(), method.Pos()),
+		Markers:     map[string]string{},
+		FuncDefInfo: typeToTypeInfo(method.Type(), fileCache).Func,
+	}
+}
+
+// localStructInfo returns the parsed struct of a non generic type declared in the package
+func localStructInfo(pi *PackageInfo, t types.Type, fileCache fileCachedData) (*StructInfo, bool) {
+	named, ok := types.Unalias(t).(*types.Named)
+	if !ok || named.Obj().Pkg() != fileCache.types || named.TypeArgs().Len() > 0 {
+		return nil, false
+	}
+
+	si, ok := pi.Structs[named.Obj().Name()]
+
+	return si, ok
+}
+
+func derefType(t types.Type) types.Type {
+	if p, ok := types.Unalias(t).(*types.Pointer); ok {
+		return p.Elem()
+	}
+
+	return t
+}
+
+func derefStruct(t types.Type) (*types.Struct, bool) {
+	st, ok := derefType(t).Underlying().(*types.Struct)
+
+	return st, ok
+}
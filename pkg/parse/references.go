@@ -0,0 +1,27 @@
+package parse
+
+// linkReferences points the named types met while parsing to their declarations in the results
+func linkReferences(results *Results, references map[string][]*TypeInfo) {
+	for _, pi := range results.Packages {
+		for name, si := range pi.Structs {
+			for _, ti := range references[qualifiedName(pi.Path, name)] {
+				ti.StructDecl = si
+			}
+		}
+		for name, ii := range pi.Interfaces {
+			for _, ti := range references[qualifiedName(pi.Path, name)] {
+				ti.InterfaceDecl = ii
+			}
+		}
+		for name, dti := range pi.DefinedTypes {
+			for _, ti := range references[qualifiedName(pi.Path, name)] {
+				ti.DefinedTypeDecl = dti
+			}
+		}
+		for name, ati := range pi.Aliases {
+			for _, ti := range references[qualifiedName(pi.Path, name)] {
+				ti.AliasDecl = ati
+			}
+		}
+	}
+}
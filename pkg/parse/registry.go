@@ -0,0 +1,287 @@
+package parse
+
+import (
+	"fmt"
+	"strings"
+)
+
+type MarkerTarget string
+
+const (
+	MarkerTargetStruct        MarkerTarget = "struct"
+	MarkerTargetField         MarkerTarget = "field"
+	MarkerTargetEmbeddedField MarkerTarget = "embedded field"
+	MarkerTargetInterface     MarkerTarget = "interface"
+	MarkerTargetEmbeddedType  MarkerTarget = "embedded type"
+	MarkerTargetFunc          MarkerTarget = "func"
+	MarkerTargetMethod        MarkerTarget = "method"
+	MarkerTargetDefinedType   MarkerTarget = "defined type"
+	MarkerTargetAlias         MarkerTarget = "alias"
+	MarkerTargetConstant      MarkerTarget = "constant"
+	MarkerTargetVar           MarkerTarget = "var"
+)
+
+// MarkerArg describes an argument of a marker. The value of "+name=value" is
+// the argument with the empty name. An empty Kind accepts any value.
+type MarkerArg struct {
+	Name     string
+	Kind     MarkerValueKind
+	Required bool
+}
+
+// MarkerDefinition describes a supported marker. Markers without Targets may
+// appear on any element, methods are functions with a receiver or interface methods.
+type MarkerDefinition struct {
+	Name    string
+	Targets []MarkerTarget
+	Args    []MarkerArg
+}
+
+func (def *MarkerDefinition) arg(name string) *MarkerArg {
+	for i := range def.Args {
+		if def.Args[i].Name == name {
+			return &def.Args[i]
+		}
+	}
+
+	return nil
+}
+
+func (def *MarkerDefinition) allows(target MarkerTarget) bool {
+	if len(def.Targets) == 0 {
+		return true
+	}
+
+	for _, t := range def.Targets {
+		if t == target {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MarkerRegistry holds the markers a generator supports. Set it on Options.Markers,
+// together with a MarkerPrefix, to get diagnostics for unknown, misplaced or
+// malformed markers in Results.Diagnostics.
+type MarkerRegistry struct {
+	definitions map[string]*MarkerDefinition
+}
+
+func NewMarkerRegistry() *MarkerRegistry {
+	return &MarkerRegistry{definitions: map[string]*MarkerDefinition{}}
+}
+
+func (r *MarkerRegistry) Register(defs ...MarkerDefinition) error {
+	for i := range defs {
+		def := defs[i]
+		if def.Name == "" {
+			return fmt.Errorf("marker definition without a name")
+		}
+		if _, ok := r.definitions[def.Name]; ok {
+			return fmt.Errorf("marker %q is already registered", def.Name)
+		}
+
+		r.definitions[def.Name] = &def
+	}
+
+	return nil
+}
+
+func (r *MarkerRegistry) Lookup(name string) (*MarkerDefinition, bool) {
+	def, ok := r.definitions[name]
+
+	return def, ok
+}
+
+// Normalize returns a copy of the marker as registered, the marker itself is
+// not changed. A marker with a single named argument such as "+gen:builder:name=Foo"
+// parses as "gen:builder:name", the copy is "gen:builder" with the name argument
+// when that is registered, and argument values are converted to the registered
+// kind where that is unambiguous: a single value becomes a list of one and ints
+// and bools may be used as strings. It reports false for unknown markers.
+func (r *MarkerRegistry) Normalize(m *Marker) (*Marker, bool) {
+	nm, def, ok := r.resolve(m)
+	if !ok {
+		return nil, false
+	}
+
+	for _, name := range nm.ArgNames {
+		if arg := def.arg(name); arg != nil {
+			coerceMarkerValue(nm.Args[name], arg.Kind)
+		}
+	}
+
+	return nm, true
+}
+
+// resolve finds the definition of the marker and returns a copy of the marker
+// under the registered name, whose argument values can be changed
+func (r *MarkerRegistry) resolve(m *Marker) (*Marker, *MarkerDefinition, bool) {
+	nm := &Marker{
+		Name:     m.Name,
+		Path:     m.Path,
+		Raw:      m.Raw,
+		Position: m.Position,
+		Args:     make(map[string]*MarkerValue, len(m.Args)),
+		ArgNames: append([]string{}, m.ArgNames...),
+		err:      m.err,
+	}
+	for name, value := range m.Args {
+		copied := *value
+		nm.Args[name] = &copied
+	}
+
+	if def, ok := r.definitions[m.Name]; ok {
+		return nm, def, true
+	}
+
+	value, single := nm.Args[""]
+	if len(m.Path) < 2 || len(m.Args) > 1 || (!single && m.err == nil) {
+		return nil, nil, false
+	}
+
+	argName := m.Path[len(m.Path)-1]
+	def, ok := r.definitions[strings.Join(m.Path[:len(m.Path)-1], ":")]
+	if !ok || def.arg(argName) == nil {
+		return nil, nil, false
+	}
+
+	nm.Path = m.Path[:len(m.Path)-1]
+	nm.Name = def.Name
+	if single {
+		nm.Args = map[string]*MarkerValue{argName: value}
+		nm.ArgNames = []string{argName}
+	}
+
+	return nm, def, true
+}
+
+func (r *MarkerRegistry) validatePackage(pi *PackageInfo) []Diagnostic {
+	v := &markerValidator{registry: r, pkg: pi.Path}
+
+	for _, si := range pi.StructList {
+		v.check(MarkerTargetStruct, si.MarkerList)
+		for _, fi := range si.FieldList {
+			v.check(MarkerTargetField, fi.MarkerList)
+		}
+		for _, efi := range si.EmbeddedFieldList {
+			v.check(MarkerTargetEmbeddedField, efi.MarkerList)
+		}
+		for _, mi := range si.MethodList {
+			v.check(MarkerTargetMethod, mi.MarkerList)
+		}
+	}
+	for _, ii := range pi.InterfaceList {
+		v.check(MarkerTargetInterface, ii.MarkerList)
+		for _, mi := range ii.MethodList {
+			v.check(MarkerTargetMethod, mi.MarkerList)
+		}
+		for _, eti := range ii.EmbeddedTypeList {
+			v.check(MarkerTargetEmbeddedType, eti.MarkerList)
+		}
+	}
+	for _, fi := range pi.FunctionList {
+		v.check(MarkerTargetFunc, fi.MarkerList)
+	}
+	for _, mi := range pi.UnattachedMethods {
+		v.check(MarkerTargetMethod, mi.MarkerList)
+	}
+	for _, dti := range pi.DefinedTypeList {
+		v.check(MarkerTargetDefinedType, dti.MarkerList)
+		for _, mi := range dti.MethodList {
+			v.check(MarkerTargetMethod, mi.MarkerList)
+		}
+	}
+	for _, ati := range pi.AliasList {
+		v.check(MarkerTargetAlias, ati.MarkerList)
+	}
+	for _, ci := range pi.ConstantList {
+		v.check(MarkerTargetConstant, ci.MarkerList)
+	}
+	for _, vi := range pi.VarList {
+		v.check(MarkerTargetVar, vi.MarkerList)
+	}
+
+	return v.diagnostics
+}
+
+type markerValidator struct {
+	registry    *MarkerRegistry
+	pkg         string
+	diagnostics []Diagnostic
+}
+
+func (v *markerValidator) report(m *Marker, severity DiagnosticSeverity, format string, args ...any) {
+	v.diagnostics = append(v.diagnostics, Diagnostic{
+		Severity: severity,
+		Kind:     DiagnosticMarker,
+		Position: m.Position,
+		Package:  v.pkg,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// check only reports diagnostics, the markers are resolved and coerced on copies
+func (v *markerValidator) check(target MarkerTarget, markers []*Marker) {
+	for _, marker := range markers {
+		m, def, ok := v.registry.resolve(marker)
+		if !ok {
+			v.report(marker, SeverityWarning, "unknown marker %q", marker.Name)
+			continue
+		}
+		if !def.allows(target) {
+			v.report(m, SeverityError, "marker %q is not allowed on a %s", m.Name, target)
+			continue
+		}
+		if m.err != nil {
+			v.report(m, SeverityError, "malformed marker %q: %v", m.Name, m.err)
+			continue
+		}
+
+		for _, name := range m.ArgNames {
+			arg := def.arg(name)
+			if arg == nil && name == "" {
+				v.report(m, SeverityError, "marker %q does not take a value", m.Name)
+				continue
+			}
+			if arg == nil {
+				v.report(m, SeverityError, "unknown argument %q of marker %q", name, m.Name)
+				continue
+			}
+			if !coerceMarkerValue(m.Args[name], arg.Kind) {
+				v.report(m, SeverityError, "argument %q of marker %q must be %s, got %s", name, m.Name, arg.Kind, m.Args[name].Kind)
+			}
+		}
+
+		for _, arg := range def.Args {
+			if _, ok := m.Args[arg.Name]; arg.Required && !ok {
+				if arg.Name == "" {
+					v.report(m, SeverityError, "marker %q requires a value", m.Name)
+				} else {
+					v.report(m, SeverityError, "missing required argument %q of marker %q", arg.Name, m.Name)
+				}
+			}
+		}
+	}
+}
+
+// coerceMarkerValue converts the value to the kind where that is unambiguous:
+// a single value becomes a list of one and ints and bools may be used as strings
+func coerceMarkerValue(value *MarkerValue, kind MarkerValueKind) bool {
+	if kind == "" || value.Kind == kind {
+		return true
+	}
+
+	switch {
+	case kind == MarkerValueList && value.Kind != MarkerValueMap:
+		item := *value
+		*value = MarkerValue{Kind: MarkerValueList, Raw: value.Raw, List: []*MarkerValue{&item}}
+		return true
+	case kind == MarkerValueString && (value.Kind == MarkerValueInt || value.Kind == MarkerValueBool):
+		*value = MarkerValue{Kind: MarkerValueString, Raw: value.Raw, StringValue: value.Raw}
+		return true
+	}
+
+	return false
+}
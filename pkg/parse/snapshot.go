@@ -0,0 +1,73 @@
+package parse
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ResultsSchema and ResultsVersion identify the JSON written by Marshal, the
+// version changes when a change of the model breaks reading older snapshots.
+// The schema is described in docs/results-schema.md.
+const (
+	ResultsSchema  = "github.com/gocloud9/gen-tool/parse.Results"
+	ResultsVersion = 1
+)
+
+// ErrSnapshotVersion is returned by Unmarshal for snapshots of another schema or version
+var ErrSnapshotVersion = errors.New("unsupported results snapshot")
+
+type snapshot struct {
+	Schema  string
+	Version int
+	Results *Results
+}
+
+// Marshal writes the results as an indented JSON snapshot. Values shared by several
+// parts of the results, such as the entries of Results.Types or a struct and its
+// pointer method set, are written once and referenced, so Unmarshal restores them shared.
+func Marshal(r *Results) ([]byte, error) {
+	data, err := marshalGraph(&snapshot{
+		Schema:  ResultsSchema,
+		Version: ResultsVersion,
+		Results: r,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshal results: %w", err)
+	}
+
+	indented := &bytes.Buffer{}
+	if err := json.Indent(indented, data, "", "  "); err != nil {
+		return nil, fmt.Errorf("marshal results: %w", err)
+	}
+	indented.WriteByte('\n')
+
+	return indented.Bytes(), nil
+}
+
+// Unmarshal reads a snapshot written by Marshal, the results can be used like
+// parsed ones, e.g. to generate without loading the packages again
+func Unmarshal(data []byte) (*Results, error) {
+	header := struct {
+		Schema  string
+		Version int
+	}{}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, fmt.Errorf("unmarshal results: %w", err)
+	}
+	if header.Schema != ResultsSchema || header.Version != ResultsVersion {
+		return nil, fmt.Errorf("%w: schema %q version %d, want %q version %d",
+			ErrSnapshotVersion, header.Schema, header.Version, ResultsSchema, ResultsVersion)
+	}
+
+	s := &snapshot{}
+	if err := unmarshalGraph(data, s); err != nil {
+		return nil, fmt.Errorf("unmarshal results: %w", err)
+	}
+	if s.Results == nil {
+		return nil, fmt.Errorf("unmarshal results: no results")
+	}
+
+	return s.Results, nil
+}
@@ -0,0 +1,110 @@
+package parse
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// sourceModulePath is the module path of ParseSource files without a go.mod
+const sourceModulePath = "source"
+
+// ParseSource parses in-memory files keyed by slash separated paths relative to the
+// module root, e.g. "a.go" or "pkg/b.go". Without a "go.mod" entry the files form
+// the module "source", so "pkg/b.go" is imported as "source/pkg". Absolute paths
+// and paths leaving the module root are rejected.
+func (p *Parser) ParseSource(files map[string]string) (*Results, error) {
+	return p.ParseSourceWithOptions(files, Options{})
+}
+
+// ParseSourceWithOptions is ParseSource with parse options, Path is the throwaway
+// module directory the files are served from through the overlay. It is removed
+// once parsed, positions and package directories keep pointing into it.
+func (p *Parser) ParseSourceWithOptions(files map[string]string, opts Options) (*Results, error) {
+	for name := range files {
+		// Absolute paths and paths climbing out with ".." would be written outside the module
+		if !filepath.IsLocal(filepath.FromSlash(name)) {
+			return nil, fmt.Errorf("source file %q is not a path inside the module", name)
+		}
+	}
+
+	dir, err := os.MkdirTemp("", "gen-tool-source-")
+	if err != nil {
+		return nil, fmt.Errorf("create source module: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if _, ok := files["go.mod"]; !ok {
+		goMod := fmt.Sprintf("module %s\n\ngo %s\n", sourceModulePath, goVersion())
+		if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte(goMod), 0o644); err != nil {
+			return nil, fmt.Errorf("create source module: %w", err)
+		}
+	}
+
+	sources := map[string][]byte{}
+	for name, content := range opts.Overlay {
+		sources[name] = content
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		// go list only looks for packages in directories that exist
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil, fmt.Errorf("create source module: %w", err)
+		}
+		if name == "go.mod" {
+			if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+				return nil, fmt.Errorf("create source module: %w", err)
+			}
+			continue
+		}
+		sources[path] = []byte(content)
+	}
+
+	opts.Path = dir
+	opts.Overlay = sources
+
+	return p.ParseDirectory(opts)
+}
+
+// overlay returns the overlay of the options with absolute paths, as packages.Config expects
+func overlay(opts Options) map[string][]byte {
+	if len(opts.Overlay) == 0 {
+		return nil
+	}
+
+	dir, err := filepath.Abs(opts.Path)
+	if err != nil {
+		dir = opts.Path
+	}
+
+	files := map[string][]byte{}
+	for name, content := range opts.Overlay {
+		if !filepath.IsAbs(name) {
+			name = filepath.Join(dir, name)
+		}
+		files[name] = content
+	}
+
+	return files
+}
+
+// goVersion returns the language version of the toolchain for the go directive
+func goVersion() string {
+	version := strings.TrimPrefix(runtime.Version(), "go")
+	parts := strings.Split(version, ".")
+	if len(parts) < 2 || parts[0] != "1" {
+		return "1.24"
+	}
+
+	minor := strings.IndexFunc(parts[1], func(r rune) bool { return r < '0' || r > '9' })
+	if minor == 0 {
+		return "1.24"
+	}
+	if minor > 0 {
+		parts[1] = parts[1][:minor]
+	}
+
+	return parts[0] + "." + parts[1]
+}
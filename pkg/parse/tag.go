@@ -0,0 +1,134 @@
+package parse
+
+import (
+	"go/ast"
+	"strconv"
+	"strings"
+)
+
+// TagInfo is one key:"value" pair of a struct tag. Name is the value up to the
+// first comma and Options the comma separated rest, as used by encoding/json.
+type TagInfo struct {
+	Key     string
+	Value   string
+	Name    string
+	Options []string
+}
+
+// TagList holds the struct tag pairs in declaration order
+type TagList []*TagInfo
+
+// Lookup returns the first pair with the key, like reflect.StructTag.Lookup
+func (tl TagList) Lookup(key string) (*TagInfo, bool) {
+	for _, ti := range tl {
+		if ti.Key == key {
+			return ti, true
+		}
+	}
+
+	return nil, false
+}
+
+// Get returns the value of the key, like reflect.StructTag.Get
+func (tl TagList) Get(key string) string {
+	ti, ok := tl.Lookup(key)
+	if !ok {
+		return ""
+	}
+
+	return ti.Value
+}
+
+func (tl TagList) Keys() []string {
+	keys := make([]string, 0, len(tl))
+	for _, ti := range tl {
+		keys = append(keys, ti.Key)
+	}
+
+	return keys
+}
+
+func rawTag(tagLit *ast.BasicLit) string {
+	if tagLit == nil {
+		return ""
+	}
+
+	raw, err := strconv.Unquote(tagLit.Value)
+	if err != nil {
+		return ""
+	}
+
+	return raw
+}
+
+// parseTagList follows the conventional key:"value" format parsed by reflect.StructTag,
+// parsing stops at the first malformed pair
+func parseTagList(tag string) TagList {
+	tl := TagList{}
+
+	for tag != "" {
+		i := 0
+		for i < len(tag) && tag[i] == ' ' {
+			i++
+		}
+		tag = tag[i:]
+		if tag == "" {
+			break
+		}
+
+		i = 0
+		for i < len(tag) && tag[i] > ' ' && tag[i] != ':' && tag[i] != '"' && tag[i] != 0x7f {
+			i++
+		}
+		if i == 0 || i+1 >= len(tag) || tag[i] != ':' || tag[i+1] != '"' {
+			break
+		}
+		key := tag[:i]
+		tag = tag[i+1:]
+
+		i = 1
+		for i < len(tag) && tag[i] != '"' {
+			if tag[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(tag) {
+			break
+		}
+		qvalue := tag[:i+1]
+		tag = tag[i+1:]
+
+		value, err := strconv.Unquote(qvalue)
+		if err != nil {
+			break
+		}
+
+		name, options, _ := strings.Cut(value, ",")
+		ti := &TagInfo{
+			Key:     key,
+			Value:   value,
+			Name:    name,
+			Options: []string{},
+		}
+		if options != "" {
+			ti.Options = strings.Split(options, ",")
+		}
+		tl = append(tl, ti)
+	}
+
+	return tl
+}
+
+// parseRawTags returns the comma separated values of each key, the first pair wins for repeated keys
+func parseRawTags(raw string) map[string][]string {
+	tags := map[string][]string{}
+
+	for _, ti := range parseTagList(raw) {
+		if _, ok := tags[ti.Key]; !ok {
+			tags[ti.Key] = strings.Split(ti.Value, ",")
+		}
+	}
+
+	return tags
+}
@@ -0,0 +1,376 @@
+package parse
+
+import (
+	"fmt"
+	"go/types"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func typeToTypeInfo(t types.Type, fileCache fileCachedData) *TypeInfo {
+	varInfo := &TypeInfo{}
+
+	switch typ := t.(type) {
+	case *types.Basic:
+		varInfo.TypeName = typ.Name()
+		varInfo.ExternalTypeName = typ.Name()
+	case *types.Map:
+		varInfo.IsMap = true
+		varInfo.MapKey = typeToTypeInfo(typ.Key(), fileCache)
+		varInfo.MapValue = typeToTypeInfo(typ.Elem(), fileCache)
+		varInfo.TypeName = fmt.Sprintf("map[%s]%s", varInfo.MapKey.TypeName, varInfo.MapValue.TypeName)
+		varInfo.ExternalTypeName = fmt.Sprintf("map[%s]%s", varInfo.MapKey.ExternalTypeName, varInfo.MapValue.ExternalTypeName)
+	case *types.Slice:
+		varInfo.IsSlice = true
+		varInfo.Slice = typeToTypeInfo(typ.Elem(), fileCache)
+		varInfo.TypeName = "[]" + varInfo.Slice.TypeName
+		varInfo.ExternalTypeName = "[]" + varInfo.Slice.ExternalTypeName
+	case *types.Array:
+		varInfo.IsArray = true
+		varInfo.Array = typeToTypeInfo(typ.Elem(), fileCache)
+		varInfo.ArrayLen = typ.Len()
+		varInfo.TypeName = fmt.Sprintf("[%d]%s", typ.Len(), varInfo.Array.TypeName)
+		varInfo.ExternalTypeName = fmt.Sprintf("[%d]%s", typ.Len(), varInfo.Array.ExternalTypeName)
+	case *types.Pointer:
+		varInfo.IsPointer = true
+		varInfo.Pointer = typeToTypeInfo(typ.Elem(), fileCache)
+		varInfo.TypeName = "*" + varInfo.Pointer.TypeName
+		varInfo.ExternalTypeName = "*" + varInfo.Pointer.ExternalTypeName
+	case *types.Chan:
+		varInfo.IsChan = true
+		varInfo.Chan = typeToTypeInfo(typ.Elem(), fileCache)
+
+		prefix := "chan "
+		switch typ.Dir() {
+		case types.SendRecv:
+			varInfo.ChanDir = ChanBoth
+		case types.SendOnly:
+			varInfo.ChanDir = ChanSend
+			prefix = "chan<- "
+		case types.RecvOnly:
+			varInfo.ChanDir = ChanRecv
+			prefix = "<-chan "
+		}
+		// chan (<-chan T) needs parentheses to keep the inner direction
+		elemTypeName, elemExternalTypeName := varInfo.Chan.TypeName, varInfo.Chan.ExternalTypeName
+		if varInfo.Chan.ChanDir == ChanRecv && varInfo.ChanDir != ChanRecv {
+			elemTypeName, elemExternalTypeName = "("+elemTypeName+")", "("+elemExternalTypeName+")"
+		}
+		varInfo.TypeName = prefix + elemTypeName
+		varInfo.ExternalTypeName = prefix + elemExternalTypeName
+	case *types.Struct:
+		varInfo = structTypeInfo(typ, fileCache)
+	case *types.Interface:
+		varInfo = interfaceTypeInfo(typ, fileCache)
+	case *types.Signature:
+		params := tupleToParamInfoList(typ.Params(), typ.Variadic(), fileCache)
+		results := tupleToResultInfoList(typ.Results(), fileCache)
+
+		varInfo.IsFunc = true
+		varInfo.TypeName = funcTypeNameFromParamsAndResults(params, results, false)
+		varInfo.ExternalTypeName = funcTypeNameFromParamsAndResults(params, results, true)
+		varInfo.Func = &FuncDefInfo{
+			IsVariadic: typ.Variadic(),
+			Params:     params,
+			Results:    results,
+		}
+	case *types.TypeParam:
+		varInfo.IsTypeParam = true
+		varInfo.TypeName = typ.Obj().Name()
+		varInfo.ExternalTypeName = typ.Obj().Name()
+	case *types.Union:
+		terms := []*TypeInfo{}
+		for i := 0; i < typ.Len(); i++ {
+			termInfo := typeToTypeInfo(typ.Term(i).Type(), fileCache)
+			if typ.Term(i).Tilde() {
+				termInfo.IsApproximate = true
+				termInfo.TypeName = "~" + termInfo.TypeName
+				termInfo.ExternalTypeName = "~" + termInfo.ExternalTypeName
+			}
+			terms = append(terms, termInfo)
+		}
+		varInfo = unionTypeInfo(terms)
+	case *types.Alias:
+		varInfo = namedTypeInfo(typ.Obj(), typ.TypeArgs(), typ.Rhs(), fileCache)
+	case *types.Named:
+		varInfo = namedTypeInfo(typ.Obj(), typ.TypeArgs(), typ.Underlying(), fileCache)
+	}
+
+	return varInfo
+}
+
+func namedTypeInfo(obj *types.TypeName, typeArgs *types.TypeList, underlying types.Type, fileCache fileCachedData) *TypeInfo {
+	// Predeclared types such as error, any and comparable are used by name only
+	if obj.Pkg() == nil {
+		return &TypeInfo{
+			TypeName:         obj.Name(),
+			ExternalTypeName: obj.Name(),
+		}
+	}
+
+	varInfo := &TypeInfo{}
+	// Inside the type table named types are references to their entries
+	if !fileCache.shallow && !fileCache.expanding[obj] {
+		fileCache.expanding[obj] = true
+		var typeOf *TypeInfo
+		// Fields and methods of named structs and interfaces are described by their declarations
+		switch underlying.(type) {
+		case *types.Struct:
+			typeOf = &TypeInfo{IsStruct: true}
+		case *types.Interface:
+			typeOf = &TypeInfo{IsInterface: true}
+		default:
+			typeOf = typeToTypeInfo(underlying, fileCache)
+		}
+		delete(fileCache.expanding, obj)
+
+		*varInfo = *typeOf
+		varInfo.TypeOf = typeOf
+		varInfo.IsGeneric = false
+		varInfo.TypeArgs = nil
+		varInfo.IsImported = false
+		varInfo.ImportedType = nil
+	}
+
+	fileCache.references.add(obj, varInfo)
+
+	varInfo.IsType = true
+	varInfo.PackageName = obj.Pkg().Name()
+	varInfo.PackagePath = obj.Pkg().Path()
+	varInfo.TypeName = obj.Name()
+	varInfo.ExternalTypeName = obj.Pkg().Name() + "." + obj.Name()
+
+	if obj.Pkg() != fileCache.types {
+		varInfo.IsImported = true
+		varInfo.ImportedType = importedTypeInfo(obj, fileCache)
+		varInfo.TypeName = varInfo.ExternalTypeName
+	}
+
+	if typeArgs != nil && typeArgs.Len() > 0 {
+		varInfo.IsGeneric = true
+
+		typeNames := []string{}
+		externalTypeNames := []string{}
+		for i := 0; i < typeArgs.Len(); i++ {
+			argInfo := typeToTypeInfo(typeArgs.At(i), fileCache)
+			varInfo.TypeArgs = append(varInfo.TypeArgs, argInfo)
+			typeNames = append(typeNames, argInfo.TypeName)
+			externalTypeNames = append(externalTypeNames, argInfo.ExternalTypeName)
+		}
+
+		varInfo.TypeName = fmt.Sprintf("%s[%s]", varInfo.TypeName, strings.Join(typeNames, ", "))
+		varInfo.ExternalTypeName = fmt.Sprintf("%s[%s]", varInfo.ExternalTypeName, strings.Join(externalTypeNames, ", "))
+	}
+
+	return varInfo
+}
+
+func importedTypeInfo(obj *types.TypeName, fileCache fileCachedData) *ImportedTypeInfo {
+	iti := &ImportedTypeInfo{
+		TypeName:            obj.Name(),
+		ImportRaw:           strconv.Quote(obj.Pkg().Path()),
+		PackagePath:         obj.Pkg().Path(),
+		PackageDefaultAlias: obj.Pkg().Name(),
+	}
+
+	imported, ok := fileCache.imports[obj.Pkg().Path()]
+	if ok {
+		iti.ImportRaw = imported.Path.Value
+		if imported.Name != nil {
+			iti.Alias = imported.Name.Name
+		}
+	}
+
+	return iti
+}
+
+func structTypeInfo(typ *types.Struct, fileCache fileCachedData) *TypeInfo {
+	si := &StructInfo{
+		Markers:        map[string]string{},
+		Fields:         map[string]*FieldInfo{},
+		Methods:        map[string]*FuncInfo{},
+		EmbeddedFields: map[string]EmbeddedFieldInfo{},
+	}
+
+	typeNames := []string{}
+	externalTypeNames := []string{}
+	for i := 0; i < typ.NumFields(); i++ {
+		field := typ.Field(i)
+		fieldInfo := typeToTypeInfo(field.Type(), fileCache)
+
+		tag := ""
+		if typ.Tag(i) != "" {
+			tag = " " + quoteTag(typ.Tag(i))
+		}
+
+		if field.Embedded() {
+			efi := EmbeddedFieldInfo{
+				Name:     field.Name(),
+				Markers:  map[string]string{},
+				RawTag:   typ.Tag(i),
+				Tags:     parseRawTags(typ.Tag(i)),
+				TagList:  parseTagList(typ.Tag(i)),
+				TypeInfo: fieldInfo,
+			}
+			si.EmbeddedFields[efi.Name] = efi
+			si.EmbeddedFieldList = append(si.EmbeddedFieldList, efi)
+
+			typeNames = append(typeNames, fieldInfo.TypeName+tag)
+			externalTypeNames = append(externalTypeNames, fieldInfo.ExternalTypeName+tag)
+			continue
+		}
+
+		fi := &FieldInfo{
+			Name:     field.Name(),
+			Markers:  map[string]string{},
+			RawTag:   typ.Tag(i),
+			Tags:     parseRawTags(typ.Tag(i)),
+			TagList:  parseTagList(typ.Tag(i)),
+			TypeInfo: fieldInfo,
+		}
+		si.Fields[fi.Name] = fi
+		si.FieldList = append(si.FieldList, fi)
+
+		typeNames = append(typeNames, field.Name()+" "+fieldInfo.TypeName+tag)
+		externalTypeNames = append(externalTypeNames, field.Name()+" "+fieldInfo.ExternalTypeName+tag)
+	}
+
+	return &TypeInfo{
+		IsStruct:         true,
+		Struct:           si,
+		TypeName:         "struct{" + strings.Join(typeNames, "; ") + "}",
+		ExternalTypeName: "struct{" + strings.Join(externalTypeNames, "; ") + "}",
+	}
+}
+
+func interfaceTypeInfo(typ *types.Interface, fileCache fileCachedData) *TypeInfo {
+	ii := &InterfaceInfo{
+		Markers:       map[string]string{},
+		Methods:       map[string]*FuncInfo{},
+		EmbeddedTypes: map[string]*EmbeddedTypeInfo{},
+	}
+
+	typeNames := []string{}
+	externalTypeNames := []string{}
+	for i := 0; i < typ.NumEmbeddeds(); i++ {
+		embeddedInfo := typeToTypeInfo(typ.EmbeddedType(i), fileCache)
+		if _, ok := typ.EmbeddedType(i).Underlying().(*types.Interface); !ok {
+			ii.Constraints = append(ii.Constraints, embeddedInfo)
+		} else {
+			eti := &EmbeddedTypeInfo{
+				Name:     embeddedInfo.TypeName,
+				Markers:  map[string]string{},
+				TypeInfo: embeddedInfo,
+			}
+			if named, ok := typ.EmbeddedType(i).(*types.Named); ok {
+				eti.Name = named.Obj().Name()
+			}
+			ii.EmbeddedTypes[eti.Name] = eti
+			ii.EmbeddedTypeList = append(ii.EmbeddedTypeList, eti)
+		}
+
+		typeNames = append(typeNames, embeddedInfo.TypeName)
+		externalTypeNames = append(externalTypeNames, embeddedInfo.ExternalTypeName)
+	}
+	// Explicit methods are sorted by name, restore the declaration order
+	methods := make([]*types.Func, 0, typ.NumExplicitMethods())
+	for i := 0; i < typ.NumExplicitMethods(); i++ {
+		methods = append(methods, typ.ExplicitMethod(i))
+	}
+	sort.SliceStable(methods, func(i, j int) bool {
+		return methods[i].Pos() < methods[j].Pos()
+	})
+
+	for _, method := range methods {
+		methodInfo := typeToTypeInfo(method.Type(), fileCache)
+
+		fi := &FuncInfo{
+			Name:        method.Name(),
+			Markers:     map[string]string{},
+			FuncDefInfo: methodInfo.Func,
+		}
+		ii.Methods[fi.Name] = fi
+		ii.MethodList = append(ii.MethodList, fi)
+
+		typeNames = append(typeNames, method.Name()+strings.TrimPrefix(methodInfo.TypeName, "func"))
+		externalTypeNames = append(externalTypeNames, method.Name()+strings.TrimPrefix(methodInfo.ExternalTypeName, "func"))
+	}
+
+	return &TypeInfo{
+		IsInterface:      true,
+		Interface:        ii,
+		TypeName:         "interface{" + strings.Join(typeNames, "; ") + "}",
+		ExternalTypeName: "interface{" + strings.Join(externalTypeNames, "; ") + "}",
+	}
+}
+
+func quoteTag(tag string) string {
+	if strings.Contains(tag, "`") {
+		return strconv.Quote(tag)
+	}
+
+	return "`" + tag + "`"
+}
+
+func ellipsisTypeInfo(elem *TypeInfo) *TypeInfo {
+	return &TypeInfo{
+		IsEllipsis:       true,
+		Ellipsis:         elem,
+		TypeName:         "..." + elem.TypeName,
+		ExternalTypeName: "..." + elem.ExternalTypeName,
+	}
+}
+
+func unionTypeInfo(terms []*TypeInfo) *TypeInfo {
+	varInfo := &TypeInfo{
+		IsUnion: true,
+		Union:   terms,
+	}
+
+	typeNames := []string{}
+	externalTypeNames := []string{}
+	for _, term := range terms {
+		typeNames = append(typeNames, term.TypeName)
+		externalTypeNames = append(externalTypeNames, term.ExternalTypeName)
+	}
+	varInfo.TypeName = strings.Join(typeNames, " | ")
+	varInfo.ExternalTypeName = strings.Join(externalTypeNames, " | ")
+
+	return varInfo
+}
+
+func tupleToParamInfoList(tuple *types.Tuple, variadic bool, fileCache fileCachedData) []*ParamInfo {
+	ps := []*ParamInfo{}
+
+	for i := 0; i < tuple.Len(); i++ {
+		param := tuple.At(i)
+
+		var typeInfo *TypeInfo
+		if variadic && i == tuple.Len()-1 {
+			typeInfo = ellipsisTypeInfo(typeToTypeInfo(param.Type().(*types.Slice).Elem(), fileCache))
+		} else {
+			typeInfo = typeToTypeInfo(param.Type(), fileCache)
+		}
+
+		ps = append(ps, &ParamInfo{
+			Name:     param.Name(),
+			TypeInfo: typeInfo,
+		})
+	}
+
+	return ps
+}
+
+func tupleToResultInfoList(tuple *types.Tuple, fileCache fileCachedData) []*ResultInfo {
+	rs := []*ResultInfo{}
+
+	for i := 0; i < tuple.Len(); i++ {
+		rs = append(rs, &ResultInfo{
+			Name:     tuple.At(i).Name(),
+			TypeInfo: typeToTypeInfo(tuple.At(i).Type(), fileCache),
+		})
+	}
+
+	return rs
+}
@@ -0,0 +1,271 @@
+package parse
+
+import (
+	"go/ast"
+	"go/types"
+	"sort"
+)
+
+// NamedTypeInfo is the single entry of a named type in Results.Types, every TypeInfo
+// naming the type points to it. Underlying describes the declaration as seen from
+// its own package, the named types it uses are references to their entries and are
+// not expanded, so walking the table always ends. Underlying is nil for types
+// declared outside the parsed packages, generic types are described once with
+// their type parameters.
+type NamedTypeInfo struct {
+	Name        string
+	PackageName string
+	PackagePath string
+	IsAlias     bool
+	Underlying  *TypeInfo
+	// IsRecursive is set when the type refers to itself, directly or through other named types
+	IsRecursive bool
+}
+
+type typeTable struct {
+	roots map[string]bool
+	types map[string]*NamedTypeInfo
+}
+
+func newTypeTable(roots map[string]bool) *typeTable {
+	return &typeTable{
+		roots: roots,
+		types: map[string]*NamedTypeInfo{},
+	}
+}
+
+// typeReferences collects the named types met while parsing and the TypeInfos naming them, keyed "pkgpath.Name"
+type typeReferences struct {
+	infos   map[string][]*TypeInfo
+	objects map[string]*types.TypeName
+}
+
+func newTypeReferences() *typeReferences {
+	return &typeReferences{
+		infos:   map[string][]*TypeInfo{},
+		objects: map[string]*types.TypeName{},
+	}
+}
+
+func (r *typeReferences) add(obj *types.TypeName, ti *TypeInfo) {
+	key := r.declare(obj)
+	r.infos[key] = append(r.infos[key], ti)
+}
+
+func (r *typeReferences) declare(obj *types.TypeName) string {
+	key := qualifiedName(obj.Pkg().Path(), obj.Name())
+	r.objects[key] = obj
+
+	return key
+}
+
+func (r *typeReferences) merge(other *typeReferences) {
+	for key, infos := range other.infos {
+		r.infos[key] = append(r.infos[key], infos...)
+	}
+	for key, obj := range other.objects {
+		r.objects[key] = obj
+	}
+}
+
+// declareTypes adds the types declared in the parsed files of the package, used or not
+func declareTypes(pi *PackageInfo, fileCache fileCachedData) {
+	for _, name := range fileCache.types.Scope().Names() {
+		obj, ok := fileCache.types.Scope().Lookup(name).(*types.TypeName)
+		if !ok {
+			continue
+		}
+		_, isStruct := pi.Structs[name]
+		_, isInterface := pi.Interfaces[name]
+		_, isDefined := pi.DefinedTypes[name]
+		_, isAlias := pi.Aliases[name]
+		if isStruct || isInterface || isDefined || isAlias {
+			fileCache.references.declare(obj)
+		}
+	}
+}
+
+// build describes every referenced type once, the declarations add the types
+// they use until all are described, then points the references to their entries
+func (t *typeTable) build(references *typeReferences) {
+	for {
+		pending := []string{}
+		for key := range references.objects {
+			if _, ok := t.types[key]; !ok {
+				pending = append(pending, key)
+			}
+		}
+		if len(pending) == 0 {
+			break
+		}
+		sort.Strings(pending)
+
+		for _, key := range pending {
+			t.types[key] = t.describe(references.objects[key], references)
+		}
+	}
+
+	for key, infos := range references.infos {
+		for _, ti := range infos {
+			ti.Named = t.types[key]
+		}
+	}
+}
+
+// describe returns the entry of the type, the named types of its declaration are
+// only added to the references so describing always ends
+func (t *typeTable) describe(obj *types.TypeName, references *typeReferences) *NamedTypeInfo {
+	nti := &NamedTypeInfo{
+		Name:        obj.Name(),
+		PackageName: obj.Pkg().Name(),
+		PackagePath: obj.Pkg().Path(),
+		IsAlias:     obj.IsAlias(),
+	}
+	if !t.roots[obj.Pkg().Path()] {
+		return nti
+	}
+
+	declCache := fileCachedData{
+		imports:    map[string]*ast.ImportSpec{},
+		types:      obj.Pkg(),
+		expanding:  map[*types.TypeName]bool{},
+		references: references,
+		shallow:    true,
+	}
+
+	switch typ := obj.Type().(type) {
+	case *types.Alias:
+		nti.Underlying = typeToTypeInfo(typ.Rhs(), declCache)
+	default:
+		nti.Underlying = typeToTypeInfo(typ.Underlying(), declCache)
+	}
+
+	return nti
+}
+
+// markRecursive flags the types taking part in a cycle of the table, and the references to them
+func (t *typeTable) markRecursive(references map[string][]*TypeInfo) {
+	keys := make([]string, 0, len(t.types))
+	for key := range t.types {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	edges := map[*NamedTypeInfo][]*NamedTypeInfo{}
+	for _, key := range keys {
+		nti := t.types[key]
+		walkNamedTypes(nti.Underlying, func(ref *NamedTypeInfo) {
+			edges[nti] = append(edges[nti], ref)
+		})
+	}
+
+	// Tarjan's strongly connected components, a type is recursive when its
+	// component has several types or when it refers to itself
+	index := map[*NamedTypeInfo]int{}
+	lowLink := map[*NamedTypeInfo]int{}
+	onStack := map[*NamedTypeInfo]bool{}
+	stack := []*NamedTypeInfo{}
+
+	var connect func(nti *NamedTypeInfo)
+	connect = func(nti *NamedTypeInfo) {
+		index[nti] = len(index)
+		lowLink[nti] = index[nti]
+		stack = append(stack, nti)
+		onStack[nti] = true
+
+		for _, ref := range edges[nti] {
+			if _, visited := index[ref]; !visited {
+				connect(ref)
+				lowLink[nti] = min(lowLink[nti], lowLink[ref])
+			} else if onStack[ref] {
+				lowLink[nti] = min(lowLink[nti], index[ref])
+			}
+			if ref == nti {
+				nti.IsRecursive = true
+			}
+		}
+
+		if lowLink[nti] != index[nti] {
+			return
+		}
+
+		component := []*NamedTypeInfo{}
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			component = append(component, top)
+			if top == nti {
+				break
+			}
+		}
+		if len(component) > 1 {
+			for _, member := range component {
+				member.IsRecursive = true
+			}
+		}
+	}
+
+	for _, key := range keys {
+		if _, visited := index[t.types[key]]; !visited {
+			connect(t.types[key])
+		}
+	}
+
+	for _, key := range keys {
+		for _, ti := range references[key] {
+			ti.IsRecursive = t.types[key].IsRecursive
+		}
+	}
+}
+
+// walkNamedTypes calls visit for the named types the type uses, without following them
+func walkNamedTypes(ti *TypeInfo, visit func(*NamedTypeInfo)) {
+	if ti == nil {
+		return
+	}
+	if ti.Named != nil {
+		visit(ti.Named)
+	}
+
+	for _, elem := range []*TypeInfo{ti.MapKey, ti.MapValue, ti.Slice, ti.Chan, ti.Array, ti.Pointer, ti.Ellipsis, ti.TypeOf} {
+		walkNamedTypes(elem, visit)
+	}
+	for _, elem := range append(append([]*TypeInfo{}, ti.TypeArgs...), ti.Union...) {
+		walkNamedTypes(elem, visit)
+	}
+
+	if ti.Struct != nil {
+		for _, fi := range ti.Struct.FieldList {
+			walkNamedTypes(fi.TypeInfo, visit)
+		}
+		for _, efi := range ti.Struct.EmbeddedFieldList {
+			walkNamedTypes(efi.TypeInfo, visit)
+		}
+	}
+	if ti.Interface != nil {
+		for _, eti := range ti.Interface.EmbeddedTypeList {
+			walkNamedTypes(eti.TypeInfo, visit)
+		}
+		for _, constraint := range ti.Interface.Constraints {
+			walkNamedTypes(constraint, visit)
+		}
+		for _, fi := range ti.Interface.MethodList {
+			walkFuncNamedTypes(fi.FuncDefInfo, visit)
+		}
+	}
+	walkFuncNamedTypes(ti.Func, visit)
+}
+
+func walkFuncNamedTypes(fdi *FuncDefInfo, visit func(*NamedTypeInfo)) {
+	if fdi == nil {
+		return
+	}
+
+	for _, pi := range fdi.Params {
+		walkNamedTypes(pi.TypeInfo, visit)
+	}
+	for _, ri := range fdi.Results {
+		walkNamedTypes(ri.TypeInfo, visit)
+	}
+}